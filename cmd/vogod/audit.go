@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/speters/vogod/pkg/vogo"
+)
+
+var auditFile = flag.String("audit", "", "append a record of every write to `file` in JSON lines format, see GET /audit")
+
+// auditLog appends the writes to the device to a file, one JSON object per line
+type auditLog struct {
+	fn string
+	mu sync.Mutex
+	f  *os.File
+}
+
+// audit is the audit log, nil if disabled
+var audit *auditLog
+
+func openAuditLog(fn string) (*auditLog, error) {
+	f, err := os.OpenFile(fn, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("can't open audit log: %w", err)
+	}
+	return &auditLog{fn: fn, f: f}, nil
+}
+
+// record appends a write to the audit log, it is used as Device.OnWrite
+func (a *auditLog) record(rec vogo.WriteRecord) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		log.Errorf("Can't record write of %v in audit log: %s", rec.ID, err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.f.Write(append(b, '\n'))
+	if err != nil {
+		log.Errorf("Can't record write of %v in audit log: %s", rec.ID, err)
+	}
+}
+
+// trustedProxies returns the networks of the reverse proxies trusted to authenticate users
+func trustedProxies(c *config) ([]netip.Prefix, error) {
+	var ps []netip.Prefix
+	for _, s := range c.Audit.TrustedProxies {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			a, errA := netip.ParseAddr(s)
+			if errA != nil {
+				return nil, fmt.Errorf("audit.trusted_proxies: %w", err)
+			}
+			p = netip.PrefixFrom(a, a.BitLen())
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+// writeOrigin tells who sent the request, for the audit log. vogod does not authenticate users itself, so the user
+// is only taken from the audit.user_header set by a trusted reverse proxy, which also forwards the client address.
+// User names sent by other clients via that header or basic auth are recorded as claimed only.
+func writeOrigin(r *http.Request) vogo.WriteOrigin {
+	c := conf()
+	var o vogo.WriteOrigin
+
+	o.Client = r.RemoteAddr
+	if host, _, err := net.SplitHostPort(o.Client); err == nil {
+		o.Client = host
+	}
+
+	ps, _ := trustedProxies(c)
+	if a, err := netip.ParseAddr(o.Client); err == nil && c.Audit.UserHeader != "" {
+		for _, p := range ps {
+			if p.Contains(a.Unmap()) {
+				// The proxy appends the address of its client
+				if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+					hops := strings.Split(xff[len(xff)-1], ",")
+					o.Client = strings.TrimSpace(hops[len(hops)-1])
+				}
+				o.User = r.Header.Get(c.Audit.UserHeader)
+				return o
+			}
+		}
+	}
+
+	if user, _, ok := r.BasicAuth(); ok {
+		o.ClaimedUser = user
+	} else if c.Audit.UserHeader != "" {
+		o.ClaimedUser = r.Header.Get(c.Audit.UserHeader)
+	}
+	return o
+}
+
+// auditRejected records a write which is rejected before reaching the device in the audit log.
+// Writes reaching the device are recorded by the device itself.
+func auditRejected(r *http.Request, rec vogo.WriteRecord, err error) {
+	if audit != nil {
+		audit.record(rec.Complete(writeOrigin(r), err))
+	}
+}
+
+// rejectWrite responds to a write which is rejected before reaching the device and records it in the audit log
+func rejectWrite(w http.ResponseWriter, r *http.Request, code int, msg string, rec vogo.WriteRecord) {
+	auditRejected(r, rec, errors.New(msg))
+	httpError(w, code, msg)
+}
+
+// parseAuditTime accepts a time in RFC 3339 format or a date like 2006-01-02 in local time
+func parseAuditTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+// get the writes recorded in the audit log, oldest first. ?from= limits them to those at or after a time given in
+// RFC 3339 format or as date, ?id= to the given EventType IDs or aliases.
+func getAudit(w http.ResponseWriter, r *http.Request) {
+	if audit == nil {
+		httpError(w, http.StatusNotFound, "Audit log is disabled, see -audit")
+		return
+	}
+
+	var from time.Time
+	if s := r.URL.Query().Get("from"); s != "" {
+		var err error
+		from, err = parseAuditTime(s)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, fmt.Sprintf("Invalid time %q, use RFC 3339 like 2006-01-02T15:04:05Z or a date like 2006-01-02", s))
+			return
+		}
+	}
+	ids := make(map[string]bool)
+	for _, id := range r.URL.Query()["id"] {
+		if et, ok := conn.DataPoint().EventType(id); ok {
+			id = et.ID
+		}
+		ids[id] = true
+	}
+
+	f, err := os.Open(audit.fn)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer f.Close()
+
+	records := []json.RawMessage{}
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1024*1024)
+	for s.Scan() {
+		var rec struct {
+			Time time.Time `json:"time"`
+			ID   string    `json:"id"`
+		}
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			// Skip a line torn by a crash
+			continue
+		}
+		if rec.Time.Before(from) || (len(ids) > 0 && !ids[rec.ID]) {
+			continue
+		}
+		records = append(records, json.RawMessage(append([]byte{}, s.Bytes()...)))
+	}
+	if err := s.Err(); err != nil {
+		httpError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	e := json.NewEncoder(w)
+	e.SetIndent("", "    ")
+	e.Encode(records)
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/speters/vogod/pkg/vogo"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigFile = "/etc/vogod/vogod.yaml"
+
+var configFile = flag.String("config", "", "configuration `file` in YAML format (default \""+defaultConfigFile+"\", if present)")
+
+// config is the layout of the configuration file:
+//
+//	connection: socket://192.168.1.10:3002   # like -c
+//	offline: false                            # like -offline
+//	datapoint: VScotHO1_72                    # like -dp
+//	ident: ""                                 # like -ident
+//	definitions:
+//	  datapoints: /usr/share/vogod/ecnDataPointType.xml   # like -d
+//	  eventtypes: /usr/share/vogod/ecnEventType.xml       # like -e
+//	  defs: ""                                            # like -defs
+//	  bundle: ""                                          # like -b
+//	  overlay: ""                                         # like -o
+//	  faults: ""                                          # like -f
+//	  aliases: ""                                         # like -a
+//	  textresources: ""                                   # like -t
+//	http:
+//	  listen: [":8000"]                       # like -s, several addresses are possible
+//	  webroot: ""                             # like -webroot
+//	cache:
+//	  duration: 3s                            # 0 disables caching of raw reads
+//	log:
+//	  level: info                             # debug, info, warn or error; -v sets debug
+//	write:
+//	  readonly: false                         # like -readonly
+//	  allow: ["Bedien*"]                      # glob patterns of writable EventType IDs, all if empty
+//	  deny: ["Codieradresse*"]                # glob patterns of EventType IDs which must not be written
+//	raw:
+//	  writable: ["0x2300-0x23FF"]             # address ranges open to POST /raw/{addr}, raw writes are refused if empty
+//	audit:
+//	  file: /var/lib/vogod/audit.jsonl        # like -audit
+//	  trusted_proxies: ["127.0.0.1"]          # addresses or networks of reverse proxies authenticating users
+//	  user_header: X-Remote-User              # header holding the user authenticated by a trusted proxy
+//
+// Each key can also be given as environment variable VOGOD_ followed by its path, like VOGOD_HTTP_LISTEN (comma
+// separated for lists). Flags take precedence over environment variables, which take precedence over the file.
+type config struct {
+	Connection string `yaml:"connection"`
+	Offline    bool   `yaml:"offline"`
+	DataPoint  string `yaml:"datapoint"`
+	Ident      string `yaml:"ident"`
+
+	Definitions struct {
+		DataPoints    string `yaml:"datapoints"`
+		EventTypes    string `yaml:"eventtypes"`
+		Defs          string `yaml:"defs"`
+		Bundle        string `yaml:"bundle"`
+		Overlay       string `yaml:"overlay"`
+		Faults        string `yaml:"faults"`
+		Aliases       string `yaml:"aliases"`
+		TextResources string `yaml:"textresources"`
+	} `yaml:"definitions"`
+
+	HTTP struct {
+		Listen  []string `yaml:"listen"`
+		WebRoot string   `yaml:"webroot"`
+	} `yaml:"http"`
+
+	Cache struct {
+		Duration *time.Duration `yaml:"duration"` // nil keeps the default of the Device
+	} `yaml:"cache"`
+
+	Log struct {
+		Level string `yaml:"level"`
+	} `yaml:"log"`
+
+	Write struct {
+		ReadOnly bool     `yaml:"readonly"`
+		Allow    []string `yaml:"allow"`
+		Deny     []string `yaml:"deny"`
+	} `yaml:"write"`
+
+	Raw struct {
+		Writable []string `yaml:"writable"`
+	} `yaml:"raw"`
+
+	Audit struct {
+		File           string   `yaml:"file"`
+		TrustedProxies []string `yaml:"trusted_proxies"`
+		UserHeader     string   `yaml:"user_header"`
+	} `yaml:"audit"`
+}
+
+// setting ties a key of the configuration file to its environment variable and flag
+type setting struct {
+	key   string // path like "http.webroot", the environment variable is VOGOD_HTTP_WEBROOT
+	flag  string // name of the flag, if any
+	value func(c *config) interface{}
+}
+
+var settings = []setting{
+	{"connection", "c", func(c *config) interface{} { return &c.Connection }},
+	{"offline", "offline", func(c *config) interface{} { return &c.Offline }},
+	{"datapoint", "dp", func(c *config) interface{} { return &c.DataPoint }},
+	{"ident", "ident", func(c *config) interface{} { return &c.Ident }},
+	{"definitions.datapoints", "d", func(c *config) interface{} { return &c.Definitions.DataPoints }},
+	{"definitions.eventtypes", "e", func(c *config) interface{} { return &c.Definitions.EventTypes }},
+	{"definitions.defs", "defs", func(c *config) interface{} { return &c.Definitions.Defs }},
+	{"definitions.bundle", "b", func(c *config) interface{} { return &c.Definitions.Bundle }},
+	{"definitions.overlay", "o", func(c *config) interface{} { return &c.Definitions.Overlay }},
+	{"definitions.faults", "f", func(c *config) interface{} { return &c.Definitions.Faults }},
+	{"definitions.aliases", "a", func(c *config) interface{} { return &c.Definitions.Aliases }},
+	{"definitions.textresources", "t", func(c *config) interface{} { return &c.Definitions.TextResources }},
+	{"http.listen", "s", func(c *config) interface{} { return &c.HTTP.Listen }},
+	{"http.webroot", "webroot", func(c *config) interface{} { return &c.HTTP.WebRoot }},
+	{"cache.duration", "", func(c *config) interface{} { return &c.Cache.Duration }},
+	{"log.level", "", func(c *config) interface{} { return &c.Log.Level }},
+	{"write.readonly", "readonly", func(c *config) interface{} { return &c.Write.ReadOnly }},
+	{"write.allow", "", func(c *config) interface{} { return &c.Write.Allow }},
+	{"write.deny", "", func(c *config) interface{} { return &c.Write.Deny }},
+	{"raw.writable", "", func(c *config) interface{} { return &c.Raw.Writable }},
+	{"audit.file", "audit", func(c *config) interface{} { return &c.Audit.File }},
+	{"audit.trusted_proxies", "", func(c *config) interface{} { return &c.Audit.TrustedProxies }},
+	{"audit.user_header", "", func(c *config) interface{} { return &c.Audit.UserHeader }},
+}
+
+// current is the effective configuration, merged from file, environment and flags. It is replaced as a whole on
+// reload, read it via conf().
+var current atomic.Pointer[config]
+
+// conf returns the effective configuration, which must not be modified
+func conf() *config {
+	return current.Load()
+}
+
+// cmdLineFlags holds the flags given on the command line, they are not overridden by the configuration
+var cmdLineFlags map[string]bool
+
+func (s setting) env() string {
+	return "VOGOD_" + strings.ToUpper(strings.ReplaceAll(s.key, ".", "_"))
+}
+
+// setValue parses s into the setting value p
+func setValue(p interface{}, s string) error {
+	switch p := p.(type) {
+	case *string:
+		*p = s
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*p = b
+	case *[]string:
+		*p = nil
+		for _, e := range strings.Split(s, ",") {
+			if e = strings.TrimSpace(e); e != "" {
+				*p = append(*p, e)
+			}
+		}
+	case **time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*p = &d
+	}
+	return nil
+}
+
+// readConfig reads the configuration file and the environment, without looking at the flags
+func readConfig() (c config, fn string, err error) {
+	fn = *configFile
+	if fn == "" {
+		fn = os.Getenv("VOGOD_CONFIG")
+	}
+	if fn == "" {
+		fn = defaultConfigFile
+		if _, err := os.Stat(fn); err != nil {
+			fn = ""
+		}
+	}
+
+	if fn != "" {
+		f, err := os.Open(fn)
+		if err != nil {
+			return c, fn, err
+		}
+		d := yaml.NewDecoder(f)
+		d.KnownFields(true)
+		err = d.Decode(&c)
+		f.Close()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return c, fn, fmt.Errorf("error loading configuration %s: %w", fn, err)
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env()); ok {
+			err = setValue(s.value(&c), v)
+			if err != nil {
+				return c, fn, fmt.Errorf("environment variable %s: %w", s.env(), err)
+			}
+		}
+	}
+	return c, fn, nil
+}
+
+// loadConfig merges the configuration file and environment with the flags and makes the result the effective
+// configuration. Flags given on the command line win, the defaults of the other flags apply to unset values.
+// The flags themselves are left untouched, as they are read concurrently.
+func loadConfig() error {
+	if cmdLineFlags == nil {
+		cmdLineFlags = make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) { cmdLineFlags[f.Name] = true })
+	}
+
+	c, fn, err := readConfig()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range settings {
+		if s.flag == "" {
+			continue
+		}
+		f := flag.Lookup(s.flag)
+		p := s.value(&c)
+		if cmdLineFlags[s.flag] {
+			setValue(p, f.Value.String())
+			continue
+		}
+		if p, ok := p.(*string); ok && *p == "" {
+			*p = f.DefValue
+		}
+	}
+	if *verbose {
+		c.Log.Level = "debug"
+	}
+
+	current.Store(&c)
+	if fn != "" {
+		log.Debugf("Loaded configuration %s", fn)
+	}
+	return nil
+}
+
+// setLogLevel applies the log level of the configuration
+func setLogLevel() error {
+	level := log.InfoLevel
+	if c := conf(); c.Log.Level != "" {
+		var err error
+		level, err = log.ParseLevel(c.Log.Level)
+		if err != nil {
+			return err
+		}
+	}
+	log.SetLevel(level)
+	if level >= log.DebugLevel {
+		log.SetFormatter(&log.TextFormatter{
+			FullTimestamp: true,
+		})
+	}
+	return nil
+}
+
+// writePolicy returns the WritePolicy of the configuration c
+func writePolicy(c *config) (*vogo.WritePolicy, error) {
+	wp := &vogo.WritePolicy{ReadOnly: c.Write.ReadOnly, Allow: c.Write.Allow, Deny: c.Write.Deny}
+	err := wp.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("write: %w", err)
+	}
+	for _, s := range c.Raw.Writable {
+		r, err := vogo.ParseAddressRange(s)
+		if err != nil {
+			return nil, fmt.Errorf("raw.writable: %w", err)
+		}
+		wp.Raw = append(wp.Raw, r)
+	}
+	return wp, nil
+}
+
+// reloadConfig reads the configuration again on SIGHUP. Definition paths, the log level and the write policy take
+// effect immediately, changes to the connection, the http server, the cache or the audit log need a restart.
+func reloadConfig() error {
+	prev := conf()
+	err := loadConfig()
+	if err != nil {
+		return err
+	}
+	err = setLogLevel()
+	if err != nil {
+		return err
+	}
+	c := conf()
+	wp, err := writePolicy(c)
+	if err != nil {
+		return err
+	}
+	_, err = trustedProxies(c)
+	if err != nil {
+		return err
+	}
+	conn.SetWritePolicy(wp)
+
+	if c.Connection != prev.Connection || strings.Join(c.HTTP.Listen, ",") != strings.Join(prev.HTTP.Listen, ",") ||
+		c.HTTP.WebRoot != prev.HTTP.WebRoot || fmt.Sprint(c.Cache.Duration) != fmt.Sprint(prev.Cache.Duration) ||
+		c.Audit.File != prev.Audit.File {
+		log.Warnf("Changes to connection, http, cache or audit settings take effect after a restart")
+	}
+	return nil
+}
+
+// normalizeListen accepts :[portnum] as well as [portnum] and returns the address along with the port
+func normalizeListen(addr string) (string, int, error) {
+	if i, err := strconv.Atoi(addr); err == nil {
+		addr = fmt.Sprintf(":%d", i)
+	}
+	_, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, 0, err
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return addr, 0, fmt.Errorf("invalid port in %v", addr)
+	}
+	return addr, port, nil
+}
+
+// validate checks the effective configuration and returns all problems found
+func (c *config) validate() []error {
+	var errs []error
+
+	if c.Connection == "" && !c.Offline {
+		errs = append(errs, fmt.Errorf("connection: needed unless offline is set"))
+	}
+	if c.Connection != "" {
+		u, err := url.Parse(c.Connection)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("connection: %w", err))
+		} else if u.Scheme != "socket" && u.Scheme != "tcp" && u.Scheme != "file" && u.Scheme != "" {
+			errs = append(errs, fmt.Errorf("connection: unknown scheme %v", u.Scheme))
+		}
+	}
+	if c.Offline && c.DataPoint == "" && c.Ident == "" {
+		errs = append(errs, fmt.Errorf("offline: needs datapoint or ident"))
+	}
+	if c.Ident != "" {
+		if _, err := vogo.ParseSysDeviceIdent(c.Ident); err != nil {
+			errs = append(errs, fmt.Errorf("ident: %w", err))
+		}
+	}
+
+	for _, d := range []struct{ key, fn string }{
+		{"definitions.datapoints", c.Definitions.DataPoints},
+		{"definitions.eventtypes", c.Definitions.EventTypes},
+		{"definitions.defs", c.Definitions.Defs},
+		{"definitions.overlay", c.Definitions.Overlay},
+		{"definitions.faults", c.Definitions.Faults},
+		{"definitions.aliases", c.Definitions.Aliases},
+		{"definitions.textresources", c.Definitions.TextResources},
+		{"http.webroot", c.HTTP.WebRoot},
+	} {
+		if d.fn == "" {
+			continue
+		}
+		if _, err := os.Stat(d.fn); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.key, err))
+		}
+	}
+
+	for _, addr := range c.HTTP.Listen {
+		if _, _, err := normalizeListen(addr); err != nil {
+			errs = append(errs, fmt.Errorf("http.listen: %w", err))
+		}
+	}
+	if c.Cache.Duration != nil && *c.Cache.Duration < 0 {
+		errs = append(errs, fmt.Errorf("cache.duration: must not be negative"))
+	}
+	if c.Log.Level != "" {
+		if _, err := log.ParseLevel(c.Log.Level); err != nil {
+			errs = append(errs, fmt.Errorf("log.level: %w", err))
+		}
+	}
+	if _, err := writePolicy(c); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Audit.File != "" {
+		if _, err := os.Stat(filepath.Dir(c.Audit.File)); err != nil {
+			errs = append(errs, fmt.Errorf("audit.file: %w", err))
+		}
+	}
+	if _, err := trustedProxies(c); err != nil {
+		errs = append(errs, err)
+	}
+	if len(c.Audit.TrustedProxies) > 0 && c.Audit.UserHeader == "" {
+		errs = append(errs, fmt.Errorf("audit.trusted_proxies: needs audit.user_header"))
+	}
+	return errs
+}
+
+// configCommand handles "vogod config ..." sub commands
+func configCommand(args []string) error {
+	if len(args) != 1 || args[0] != "check" {
+		return fmt.Errorf("usage: %s config check", os.Args[0])
+	}
+
+	err := loadConfig()
+	if err != nil {
+		return err
+	}
+	errs := conf().validate()
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("configuration has %v problems", len(errs))
+	}
+
+	fmt.Println("Configuration is valid, effective settings:")
+	e := yaml.NewEncoder(os.Stdout)
+	e.SetIndent(2)
+	err = e.Encode(conf())
+	if errC := e.Close(); err == nil {
+		err = errC
+	}
+	return err
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/speters/vogod/pkg/vogo"
+	"gopkg.in/yaml.v3"
+)
+
+// bundlePath returns the filename of the precompiled definition bundle
+func bundlePath(c *config) string {
+	if c.Definitions.Bundle != "" {
+		return c.Definitions.Bundle
+	}
+	return filepath.Join(filepath.Dir(c.Definitions.EventTypes), "vogod-defs.gob")
+}
+
+// defsCommand handles "vogod defs ..." sub commands
+func defsCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s defs compile|lint|export", os.Args[0])
+	}
+
+	switch args[0] {
+	case "compile":
+		return defsCompile()
+	case "lint":
+		if len(args) > 2 {
+			return fmt.Errorf("usage: %s defs lint [DataPointID]", os.Args[0])
+		}
+		id := ""
+		if len(args) == 2 {
+			id = args[1]
+		}
+		return defsLint(id)
+	case "export":
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf("usage: %s defs export DataPointID [yaml|json]", os.Args[0])
+		}
+		format := "yaml"
+		if len(args) == 3 {
+			format = args[2]
+		}
+		return defsExport(args[1], format)
+	}
+	return fmt.Errorf("unknown defs command %v", args[0])
+}
+
+// defsCompile parses the xml files once and stores them as definition bundle
+func defsCompile() error {
+	c := conf()
+	b, err := vogo.CompileDefinitions(c.Definitions.DataPoints, c.Definitions.EventTypes)
+	if err != nil {
+		return err
+	}
+
+	fn := bundlePath(c)
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	err = b.Write(f)
+	if errC := f.Close(); err == nil {
+		err = errC
+	}
+	if err != nil {
+		os.Remove(fn)
+		return err
+	}
+
+	n := 0
+	for _, dps := range b.DataPoints {
+		n += len(dps)
+	}
+	fmt.Printf("Compiled %v DataPoints and %v EventTypes (%v invalid) into %s\n", n, len(b.EventTypes), len(b.Invalid()), fn)
+	return nil
+}
+
+// defsLint checks the EventTypes of a DataPoint, or all EventTypes if id is empty, and prints the problems found
+func defsLint(id string) error {
+	var ds vogo.Diagnostics
+	c := conf()
+
+	if id != "" {
+		dpt := &vogo.DataPointType{EventTypes: make(vogo.EventTypeList)}
+
+		xmlFile, err := os.Open(c.Definitions.DataPoints)
+		if err != nil {
+			return err
+		}
+		err = vogo.FindDataPointTypeByID(xmlFile, id, dpt)
+		xmlFile.Close()
+		if err != nil {
+			return fmt.Errorf("DataPoint %v: %w", id, err)
+		}
+
+		xmlFile, err = os.Open(c.Definitions.EventTypes)
+		if err != nil {
+			return err
+		}
+		vogo.FindEventTypes(xmlFile, &dpt.EventTypes)
+		xmlFile.Close()
+		ds = dpt.Diagnostics()
+	} else {
+		xmlFile, err := os.Open(c.Definitions.EventTypes)
+		if err != nil {
+			return err
+		}
+		ds = vogo.LintEventTypes(xmlFile)
+		xmlFile.Close()
+	}
+
+	fatal := 0
+	for _, d := range ds {
+		fmt.Println(d)
+		if d.Fatal {
+			fatal++
+		}
+	}
+	fmt.Printf("%v problems found, %v of them make EventTypes unusable\n", len(ds), fatal)
+	if fatal > 0 {
+		return fmt.Errorf("definitions have errors")
+	}
+	return nil
+}
+
+// defsExport writes the EventTypes of a DataPoint from the xml files in the format used by -defs to stdout
+func defsExport(id string, format string) error {
+	c := conf()
+	dpt := &vogo.DataPointType{EventTypes: make(vogo.EventTypeList)}
+
+	xmlFile, err := os.Open(c.Definitions.DataPoints)
+	if err != nil {
+		return err
+	}
+	err = vogo.FindDataPointTypeByID(xmlFile, id, dpt)
+	xmlFile.Close()
+	if err != nil {
+		return fmt.Errorf("DataPoint %v: %w", id, err)
+	}
+
+	xmlFile, err = os.Open(c.Definitions.EventTypes)
+	if err != nil {
+		return err
+	}
+	defs := vogo.ExportEventTypeDefs(xmlFile, dpt)
+	xmlFile.Close()
+
+	switch format {
+	case "yaml":
+		e := yaml.NewEncoder(os.Stdout)
+		e.SetIndent(2)
+		err = e.Encode(defs)
+		if errC := e.Close(); err == nil {
+			err = errC
+		}
+		return err
+	case "json":
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "    ")
+		return e.Encode(defs)
+	}
+	return fmt.Errorf("unknown format %v", format)
+}
+
+// loadBundle loads the definition bundle, returns nil if it is missing or outdated
+func loadBundle(c *config) *vogo.DefinitionBundle {
+	fn := bundlePath(c)
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	b, err := vogo.ReadDefinitionBundle(f)
+	if err != nil {
+		log.Warnf("Ignoring definition bundle %s: %s", fn, err)
+		return nil
+	}
+	if !b.UpToDate(c.Definitions.DataPoints, c.Definitions.EventTypes) {
+		log.Warnf("Ignoring outdated definition bundle %s, run \"%s defs compile\" to update it", fn, os.Args[0])
+		return nil
+	}
+	return b
+}
+
+// loadDefinitions finds the DataPoint of the device and its EventTypes, using the definition bundle if possible.
+// If dpID is set, the DataPoint with that ID is used instead of the one matching sysDeviceID.
+// EventTypes given via -defs take precedence over those from xml, the overlay given via -o is applied last.
+// The files are taken from the configuration c. It returns the number of EventTypes found and announced by the DataPoint
+// and the DataPoints with the Identification of the device, see /datapoint/candidates.
+func loadDefinitions(c *config, sysDeviceID [8]byte, dpID string, dpt *vogo.DataPointType) (found int, announced int, cs []vogo.DataPointCandidate, err error) {
+	found, announced, cs, err = loadXMLDefinitions(c, sysDeviceID, dpID, dpt)
+	if err != nil {
+		return found, announced, cs, err
+	}
+
+	if c.Definitions.Defs != "" {
+		err = loadDefinitionFile(c.Definitions.Defs, dpt)
+		if err != nil {
+			return found, announced, cs, err
+		}
+	}
+	err = loadOverlay(c, dpt)
+	if err != nil {
+		return found, announced, cs, err
+	}
+
+	found = 0
+	for _, et := range dpt.EventTypes {
+		if et.Codec != nil {
+			found++
+		}
+	}
+	return found, announced, cs, nil
+}
+
+// loadDefinitionFile loads the EventTypes given via -defs
+func loadDefinitionFile(fn string, dpt *vogo.DataPointType) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defs, err := vogo.ReadDefinitionFile(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("error loading EventType definitions %s: %w", fn, err)
+	}
+
+	// Only EventTypes announced by the DataPoint are used, even if their xml definition was invalid
+	etl := make(vogo.EventTypeList)
+	for _, d := range defs.EventTypes {
+		if dpt.Announces(d.ID) {
+			etl[d.ID] = &vogo.EventType{ID: d.ID}
+		}
+	}
+	n := defs.FindEventTypes(&etl)
+	for id, et := range etl {
+		if prev, ok := dpt.EventTypes[id]; ok && et.Codec == nil && prev.Codec != nil {
+			// Keep the usable definition from xml, but report the problems
+			prev.Diagnostics = append(prev.Diagnostics, et.Diagnostics...)
+			continue
+		}
+		dpt.EventTypes[id] = et
+	}
+	log.Infof("Loaded %v EventType definitions from %s", n, fn)
+	return nil
+}
+
+// loadOverlay applies the overlay file to the EventTypes. Changes which can't be applied are logged.
+func loadOverlay(c *config, dpt *vogo.DataPointType) error {
+	fn := c.Definitions.Overlay
+	if fn == "" {
+		fn = filepath.Join(filepath.Dir(c.Definitions.EventTypes), "overlay.yaml")
+		if _, err := os.Stat(fn); err != nil {
+			return nil
+		}
+	}
+
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	ov, err := vogo.ReadOverlay(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("error loading overlay %s: %w", fn, err)
+	}
+
+	err = ov.Apply(dpt.EventTypes, fn)
+	if err != nil {
+		log.Warnf("Overlay %s: %s", fn, err)
+	}
+	log.Infof("Applied overlay %s", fn)
+	return nil
+}
+
+// loadXMLDefinitions finds the DataPoint of the device and its EventTypes in the definition bundle or the xml files,
+// along with the DataPoint candidates
+func loadXMLDefinitions(c *config, sysDeviceID [8]byte, dpID string, dpt *vogo.DataPointType) (found int, announced int, cs []vogo.DataPointCandidate, err error) {
+	if b := loadBundle(c); b != nil {
+		if dpID != "" {
+			err = b.FindDataPointTypeByID(dpID, dpt)
+		} else {
+			err = b.FindDataPointType(sysDeviceID, dpt)
+		}
+		if err != nil {
+			return 0, 0, nil, fmt.Errorf("DataPoint: %w", err)
+		}
+		setSysDeviceIdent(sysDeviceID, dpt)
+		cs, _ = findCandidates(c, b, dpt.SysDeviceIdent)
+		announced = len(dpt.EventTypes)
+		found = b.FindEventTypes(&dpt.EventTypes)
+		log.Infof("Loaded definitions from %s", bundlePath(c))
+		return found, announced, cs, nil
+	}
+
+	xmlFile, err := os.Open(c.Definitions.DataPoints)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if dpID != "" {
+		err = vogo.FindDataPointTypeByID(xmlFile, dpID, dpt)
+	} else {
+		err = vogo.FindDataPointType(xmlFile, sysDeviceID, dpt)
+	}
+	xmlFile.Close()
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("DataPoint: %w", err)
+	}
+	setSysDeviceIdent(sysDeviceID, dpt)
+	announced = len(dpt.EventTypes)
+	cs, err = findCandidates(c, nil, dpt.SysDeviceIdent)
+	if err != nil {
+		return 0, announced, nil, err
+	}
+
+	xmlFile, err = os.Open(c.Definitions.EventTypes)
+	if err != nil {
+		return 0, announced, cs, err
+	}
+	found = vogo.FindEventTypes(xmlFile, &dpt.EventTypes)
+	xmlFile.Close()
+	return found, announced, cs, nil
+}
+
+// setSysDeviceIdent keeps the ident of the device instead of the one derived from a DataPoint found by ID
+func setSysDeviceIdent(sysDeviceID [8]byte, dpt *vogo.DataPointType) {
+	if sysDeviceID != [8]byte{} {
+		dpt.SysDeviceIdent = sysDeviceID
+	}
+}
+
+// reloadDefinitions loads the definitions, overlay, fault catalog, aliases and text resources again and replaces the
+// DataPoint of the device, logging which EventTypes changed. The Optolink connection is kept.
+func reloadDefinitions() error {
+	c := conf()
+	prev := conn.DataPoint()
+	dpt := &vogo.DataPointType{EventTypes: make(vogo.EventTypeList)}
+
+	i, j, cs, err := loadDefinitions(c, prev.SysDeviceIdent, c.DataPoint, dpt)
+	if err != nil {
+		return err
+	}
+	if i == 0 {
+		return fmt.Errorf("no EventType definitions found for DataPoint %v", dpt.ID)
+	}
+	loadFaultCatalog(c, dpt)
+	loadAliases(c, dpt)
+	loadTextResources(c)
+	conn.SetDataPoint(dpt)
+	candidates.Store(&cs)
+
+	if dpt.ID != prev.ID {
+		log.Infof("DataPoint changed from %v to %v", prev.ID, dpt.ID)
+	}
+	added, removed, changed := prev.EventTypes.Diff(dpt.EventTypes)
+	log.Infof("Reloaded %v of %v EventTypes: %v added, %v removed, %v changed", i, j, len(added), len(removed), len(changed))
+	for _, l := range []struct {
+		what string
+		ids  []string
+	}{{"Added", added}, {"Removed", removed}, {"Changed", changed}} {
+		if len(l.ids) > 0 {
+			log.Infof("%s EventTypes: %s", l.what, strings.Join(l.ids, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/speters/vogod/pkg/vogo"
+)
+
+// findCandidates lists the DataPoints with the Identification of the device, using the definition bundle b if not nil
+func findCandidates(c *config, b *vogo.DefinitionBundle, sysDeviceID [8]byte) ([]vogo.DataPointCandidate, error) {
+	if b != nil {
+		return b.FindDataPointCandidates(sysDeviceID), nil
+	}
+
+	xmlFile, err := os.Open(c.Definitions.DataPoints)
+	if err != nil {
+		return nil, err
+	}
+	defer xmlFile.Close()
+	return vogo.FindDataPointCandidates(xmlFile, sysDeviceID), nil
+}
+
+// candidates are the DataPoint candidates of the loaded definitions, they are found along with the DataPoint
+var candidates atomic.Pointer[[]vogo.DataPointCandidate]
+
+// identifyCommand handles "vogod identify": it reads the ident of the device, or takes it from -ident,
+// and prints it along with the matching DataPoints
+func identifyCommand() error {
+	var sdi vogo.SysDeviceIdentT
+	var err error
+	c := conf()
+
+	if c.Ident != "" {
+		sdi, err = vogo.ParseSysDeviceIdent(c.Ident)
+		if err != nil {
+			return err
+		}
+	} else {
+		if c.Connection == "" {
+			return fmt.Errorf("need connection string in -c option or an ident in -ident option")
+		}
+		conn = vogo.NewDevice()
+		err = conn.Connect(c.Connection)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		result := conn.RawCmd(getSysDeviceIdent)
+		if result.Err != nil {
+			return fmt.Errorf("can not read device ident: %w", result.Err)
+		}
+		copy(sdi[:], result.Body)
+	}
+
+	cs, err := findCandidates(c, loadBundle(c), sdi)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Ident: % X\n", sdi[:])
+	fmt.Printf("       %v\n\n", sdi.Decode())
+	if len(cs) == 0 {
+		fmt.Printf("No DataPoint with Identification %02X%02X found\n", sdi[0], sdi[1])
+		return nil
+	}
+	for _, c := range cs {
+		mark := " "
+		if c.Chosen {
+			mark = "*"
+		}
+		fmt.Printf("%s %-24s %v..%v: %v\n", mark, c.ID, c.IdentificationExtension, c.IdentificationExtensionTill, c.Reason)
+	}
+	return nil
+}
+
+// list the DataPoints with the Identification of the device, with the reason why each was chosen or rejected
+func getCandidates(w http.ResponseWriter, r *http.Request) {
+	sdi := conn.DataPoint().SysDeviceIdent
+	cs := []vogo.DataPointCandidate{}
+	if p := candidates.Load(); p != nil {
+		cs = *p
+	}
+
+	v := struct {
+		SysDeviceIdent vogo.SysDeviceIdentT      `json:"sys_device_ident"`
+		Ident          vogo.DeviceIdent          `json:"ident"`
+		Candidates     []vogo.DataPointCandidate `json:"candidates"`
+	}{SysDeviceIdent: sdi, Ident: sdi.Decode(), Candidates: cs}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	e := json.NewEncoder(w)
+	e.SetIndent("", "    ")
+	e.Encode(v)
+}
@@ -5,17 +5,21 @@ package main
 import (
 	"embed"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io/fs"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"runtime/pprof"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -28,8 +32,19 @@ import (
 //go:embed web/*
 var webFS embed.FS
 
+// Flags with a configuration key are merged into the configuration by loadConfig, read them via conf()
 var dpFile = flag.String("d", "ecnDataPointType.xml", "filename of ecnDataPointType.xml like `file`")
 var etFile = flag.String("e", "ecnEventType.xml", "filename of ecnEventType.xml like `file`")
+var faultFile = flag.String("f", "", "fault-code catalogue `file` in JSON format (default \"faultcodes.json\" next to the -e file, if present)")
+var aliasFile = flag.String("a", "", "alias `file` in JSON format mapping stable names to EventType IDs (default \"aliases.json\" next to the -e file, if present)")
+var textDir = flag.String("t", "", "`dir` holding Textresource_xx.xml files for localized texts (default: directory of the -e file)")
+var defsFile = flag.String("defs", "", "EventType definitions `file` in YAML or JSON format, taking precedence over the -e file")
+var bundleFile = flag.String("b", "", "precompiled definition bundle `file` (default \"vogod-defs.gob\" next to the -e file)")
+var overlayFile = flag.String("o", "", "overlay `file` in YAML or JSON format to patch, add or remove EventTypes (default \"overlay.yaml\" next to the -e file, if present)")
+var forceDP = flag.String("dp", "", "use the DataPoint with this `ID` instead of identifying the device")
+var forceIdent = flag.String("ident", "", "use this device `ident` like \"20 92 01 07 00 00 01 5A\" instead of reading it from the device")
+var offline = flag.Bool("offline", false, "serve definitions even if the device is not reachable, needs -dp or -ident")
+var readOnly = flag.Bool("readonly", false, "refuse all writes to the device")
 var httpServe = flag.String("s", "", "start http server at [bindtohost][:]port")
 var connTo = flag.String("c", "", "connection string, use socket://[host]:[port] for TCP or [serialDevice] for direct serial connection ")
 var webRoot = flag.String("webroot", "", "serve web UI from `dir` instead of embedded files")
@@ -41,6 +56,9 @@ var memprofile = flag.String("memprofile", "", "write memory profile to `file`")
 
 var conn *vogo.Device
 
+// textResources holds the loaded text resources by language, replaced as a whole on reload
+var textResources atomic.Pointer[map[string]vogo.TextResources]
+
 func httpError(w http.ResponseWriter, code int, msg string) {
 	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
 	w.WriteHeader(code)
@@ -58,14 +76,32 @@ var getSysDeviceIdent vogo.FsmCmd = vogo.FsmCmd{ID: [16]byte{0, 1, 2, 3, 4, 5, 6
 
 // const testDeviceIdent = [8]byte{0x20, 0x92, 0x01, 0x07, 0x00, 0x00, 0x01, 0x5a}
 
+// linkRequired wraps handlers which access the device, answering with 503 while the link is down
+func linkRequired(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !conn.Connected() {
+			w.Header().Set("Retry-After", "15")
+			httpError(w, http.StatusServiceUnavailable, "Device not connected")
+			return
+		}
+		h(w, r)
+	}
+}
+
 // list all EventTypes for http response
 func getEventTypes(w http.ResponseWriter, r *http.Request) {
+	dp := conn.DataPoint()
 	e := json.NewEncoder(w)
 	e.SetIndent("", "    ")
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s.json\"", conn.DataPoint.ID))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s.json\"", dp.ID))
 	w.WriteHeader(http.StatusOK)
-	e.Encode(conn.DataPoint.EventTypes)
+	etl := conn.WithWritable(dp.EventTypes)
+	if tr := httpTextResources(r); tr != nil {
+		e.Encode(tr.LocalizeList(etl))
+		return
+	}
+	e.Encode(etl)
 }
 
 // get DataPoint (a Viessmann term for a device like a boiler, heater) for http response
@@ -74,7 +110,9 @@ func getDataPoint(w http.ResponseWriter, r *http.Request) {
 	e.SetIndent("", "    ")
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(http.StatusOK)
-	e.Encode(conn.DataPoint)
+	dp := *conn.DataPoint()
+	dp.Description = httpTextResources(r).Text(dp.Description)
+	e.Encode(dp)
 }
 
 // get version info of vogod for http response
@@ -93,7 +131,7 @@ func versionInfo(w http.ResponseWriter, r *http.Request) {
 // get data of an "Event" (a Viessmann term for a data point) for http response
 func getEvent(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	et, ok := conn.DataPoint.EventTypes[params["id"]]
+	et, ok := conn.DataPoint().EventType(params["id"])
 	if !ok {
 		httpError(w, http.StatusNotFound, fmt.Sprintf("No such EventType %v", params["id"]))
 		return
@@ -109,32 +147,156 @@ func getEvent(w http.ResponseWriter, r *http.Request) {
 	e := json.NewEncoder(w)
 	e.SetIndent("", "    ")
 
-	rEt := *et
+	rEt := *httpTextResources(r).Localize(et)
 	rEt.Value = b
+	rEt.Writable = conn.Writable(et)
 	e.Encode(rEt)
 }
 
+// get data of several "Events" read at once for http response. The IDs are given via ?id=.
+func getEvents(w http.ResponseWriter, r *http.Request) {
+	readEvents(w, r.URL.Query()["id"])
+}
+
+// POST /events reads the EventTypes given as JSON array like GET /events, or writes the ID/value pairs given as
+// JSON object as a whole, see setEvents
+func postEvents(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err == nil {
+		var ids []string
+		if err = json.Unmarshal(body, &ids); err == nil {
+			readEvents(w, ids)
+			return
+		}
+		var values map[string]interface{}
+		if err = json.Unmarshal(body, &values); err == nil {
+			setEvents(w, r, values)
+			return
+		}
+	}
+	// This might have been meant as write
+	rejectWrite(w, r, http.StatusBadRequest, fmt.Sprintf("Expected a JSON array of EventType IDs or an object of ID/value pairs: %s", err), vogo.WriteRecord{})
+}
+
+// maxEvents limits the EventTypes read at once, as all other reads and writes wait meanwhile
+const maxEvents = 64
+
+func readEvents(w http.ResponseWriter, ids []string) {
+	if len(ids) == 0 || len(ids) > maxEvents {
+		httpError(w, http.StatusBadRequest, fmt.Sprintf("Expected 1 to %v EventType IDs", maxEvents))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	e := json.NewEncoder(w)
+	e.SetIndent("", "    ")
+	e.Encode(conn.VReadMulti(ids...))
+}
+
+// set data of several "Events" as a whole: if any of them fails, the previous values are restored.
+// The response reports the outcome per EventType.
+func setEvents(w http.ResponseWriter, r *http.Request, values map[string]interface{}) {
+	results, err := conn.VWriteMulti(values, writeOrigin(r))
+
+	code := http.StatusOK
+	if err != nil {
+		log.Warnf("Writing %v EventTypes failed: %s", len(values), err)
+		code = http.StatusBadRequest
+		var deniedErr *vogo.WriteDeniedError
+		if errors.As(err, &deniedErr) {
+			code = http.StatusForbidden
+		}
+		for _, res := range results {
+			if res.Status == vogo.WriteRolledBack || res.Status == vogo.WriteRollbackFailed {
+				// The values were fine, but the device failed
+				code = http.StatusInternalServerError
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	e := json.NewEncoder(w)
+	e.SetIndent("", "    ")
+	e.Encode(results)
+}
+
 // set data of an "Event" (a Viessmann term for a data point or an address in the heating device containing data) from a http request
 func setEvent(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	et, ok := conn.DataPoint.EventTypes[params["id"]]
+	et, ok := conn.DataPoint().EventType(params["id"])
 	if !ok {
-		httpError(w, http.StatusNotFound, fmt.Sprintf("No such EventType %v", params["id"]))
+		rejectWrite(w, r, http.StatusNotFound, fmt.Sprintf("No such EventType %v", params["id"]), vogo.WriteRecord{ID: params["id"]})
 		return
 	}
+	rejected := vogo.WriteRecord{ID: et.ID, Address: et.Address}
 
 	decoder := json.NewDecoder(r.Body)
 	var val interface{}
 	err := decoder.Decode(&val)
 
 	if err != nil {
+		auditRejected(r, rejected, err)
 		httpError(w, http.StatusInternalServerError, fmt.Sprintf("%s\n\n%#v", err, et))
 		return
 	}
+
+	// /event/{id}/{index} writes a single element of a block-factored EventType
+	index := -1
+	if s, ok := params["index"]; ok {
+		index, err = strconv.Atoi(s)
+		if err != nil || index >= et.Elements() || et.Elements() == 1 {
+			rejectWrite(w, r, http.StatusNotFound, fmt.Sprintf("No such element %v of EventType %v", s, et.ID), rejected)
+			return
+		}
+	}
+
+	// ?clamp=1 clamps values to the borders and rounds them to the stepping of the EventType instead of rejecting them
+	clamp, _ := strconv.ParseBool(r.URL.Query().Get("clamp"))
+
+	// ?dryrun=1 shows the bytes which would be written, without writing them
+	if dryrun, _ := strconv.ParseBool(r.URL.Query().Get("dryrun")); dryrun {
+		var plan vogo.WritePlan
+		if clamp {
+			plan, err = conn.PlanWriteClamped(et.ID, index, val)
+		} else {
+			plan, err = conn.PlanWrite(et.ID, index, val)
+		}
+		if err != nil {
+			httpWriteError(w, err, et)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		e := json.NewEncoder(w)
+		e.SetIndent("", "    ")
+		e.Encode(plan)
+		return
+	}
+
 	et.Value = val
-	err = conn.VWrite(et.ID, val)
+	if clamp {
+		written, err := conn.VWriteIndexClamped(et.ID, index, val, writeOrigin(r))
+		if err != nil {
+			httpWriteError(w, err, et)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(struct {
+			Written interface{} `json:"written"`
+		}{written})
+		return
+	}
+
+	if index >= 0 {
+		err = conn.VWriteIndex(et.ID, index, val, writeOrigin(r))
+	} else {
+		err = conn.VWrite(et.ID, val, writeOrigin(r))
+	}
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, fmt.Sprintf("%s\n\n%#v", err, et))
+		httpWriteError(w, err, et)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -142,6 +304,49 @@ func setEvent(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("\"OK\"\n"))
 }
 
+// reset an "Event" to its factory default. As this may overwrite settings made by the installer, ?confirm=1 is required
+func resetEvent(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	et, ok := conn.DataPoint().EventType(params["id"])
+	if !ok {
+		rejectWrite(w, r, http.StatusNotFound, fmt.Sprintf("No such EventType %v", params["id"]), vogo.WriteRecord{ID: params["id"]})
+		return
+	}
+	rejected := vogo.WriteRecord{ID: et.ID, Address: et.Address}
+	if et.Default == nil {
+		rejectWrite(w, r, http.StatusBadRequest, fmt.Sprintf("EventType %v has no factory default", et.ID), rejected)
+		return
+	}
+	if confirm, _ := strconv.ParseBool(r.URL.Query().Get("confirm")); !confirm {
+		rejectWrite(w, r, http.StatusPreconditionRequired, fmt.Sprintf("Resetting EventType %v to %v needs ?confirm=1", et.ID, et.Default), rejected)
+		return
+	}
+
+	err := conn.VReset(et.ID, writeOrigin(r))
+	if err != nil {
+		httpWriteError(w, err, et)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("\"OK\"\n"))
+}
+
+// httpWriteError responds to a failed write, using 400 for values rejected by the EventType's constraints
+func httpWriteError(w http.ResponseWriter, err error, et *vogo.EventType) {
+	var rangeErr *vogo.RangeError
+	if errors.As(err, &rangeErr) {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var deniedErr *vogo.WriteDeniedError
+	if errors.As(err, &deniedErr) {
+		httpError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	httpError(w, http.StatusInternalServerError, fmt.Sprintf("%s\n\n%#v", err, et))
+}
+
 // get raw data like an operation on memory
 func getRaw(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
@@ -204,48 +409,226 @@ func getRaw(w http.ResponseWriter, r *http.Request) {
 	e.Encode(res.Body)
 }
 
-// set raw data in memory
+// set raw data in memory. Only if the address holds the expected bytes, they are replaced (compare-and-swap):
+// {"expected": "00 01", "data": "00 02"}. Raw writes need to be enabled for the address via raw.writable.
 func setRaw(w http.ResponseWriter, r *http.Request) {
-	httpError(w, http.StatusNotImplemented, "not implemented")
-	// TODO: this premature return is intentional to prevent potentially harmful interaction with the heating device, will decide later if we enable
-	return
-
 	params := mux.Vars(r)
-	et, ok := conn.DataPoint.EventTypes[params["id"]]
-	if !ok {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte(fmt.Sprintf("No such EventType %v", params["id"])))
+	var addr uint64
+	var err error
+	if strings.HasPrefix(params["addr"], "0x") {
+		addr, err = strconv.ParseUint(params["addr"][2:], 16, 16)
+	} else {
+		addr, err = strconv.ParseUint(params["addr"], 10, 16)
+	}
+	if err != nil {
+		rejectWrite(w, r, http.StatusBadRequest, err.Error(), vogo.WriteRecord{Raw: true})
 		return
 	}
+	rejected := vogo.WriteRecord{Raw: true, Address: vogo.AddressT(addr)}
 
-	decoder := json.NewDecoder(r.Body)
-	var val interface{}
-	err := decoder.Decode(&val)
+	var req struct {
+		Expected vogo.HexBytes `json:"expected"`
+		Data     vogo.HexBytes `json:"data"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		rejectWrite(w, r, http.StatusBadRequest, fmt.Sprintf("Expected {\"expected\": \"hex bytes\", \"data\": \"hex bytes\"}: %s", err), rejected)
+		return
+	}
+
+	if len(req.Data) == 0 || len(req.Data) > vogo.MaxRawWrite || len(req.Expected) != len(req.Data) {
+		rejectWrite(w, r, http.StatusBadRequest, fmt.Sprintf("Expected and data need the same length of 1 to %v bytes", vogo.MaxRawWrite), rejected)
+		return
+	}
 
+	old, err := conn.RawWrite(vogo.AddressT(addr), req.Expected, req.Data, writeOrigin(r))
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
-		w.Write([]byte(err.Error()))
-		w.Write([]byte(fmt.Sprintf("\n\n%#v", et)))
+		var deniedErr *vogo.WriteDeniedError
+		var mismatchErr *vogo.RawMismatchError
+		switch {
+		case errors.As(err, &deniedErr):
+			httpError(w, http.StatusForbidden, err.Error())
+		case errors.As(err, &mismatchErr):
+			httpError(w, http.StatusConflict, err.Error())
+		default:
+			httpError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
-	et.Value = val
-	err = conn.VWrite(et.ID, val)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	e := json.NewEncoder(w)
+	e.SetIndent("", "    ")
+	e.Encode(struct {
+		Address vogo.AddressT `json:"address"`
+		Old     vogo.HexBytes `json:"old"`
+		New     vogo.HexBytes `json:"new"`
+	}{vogo.AddressT(addr), old, req.Data})
+}
+
+// get the combined error history of all FehlerHis* EventTypes, newest first
+func getFaults(w http.ResponseWriter, r *http.Request) {
+	entries, err := conn.FaultHistory()
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
-		w.Write([]byte(err.Error()))
-		w.Write([]byte(fmt.Sprintf("\n\n%#v", et)))
+		httpError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("\"OK\"\n"))
+	e := json.NewEncoder(w)
+	e.SetIndent("", "    ")
+	e.Encode(entries)
+}
+
+// list all writable EventTypes whose current value differs from their factory default
+func getSettingsDiff(w http.ResponseWriter, r *http.Request) {
+	diff, err := conn.SettingsDiff()
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	e := json.NewEncoder(w)
+	e.SetIndent("", "    ")
+	e.Encode(diff)
+}
+
+// list the problems found in the definitions of the EventTypes of the DataPoint
+func getDiagnostics(w http.ResponseWriter, r *http.Request) {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "    ")
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	e.Encode(conn.DataPoint().Diagnostics())
+}
+
+// get the protocol spoken on the Optolink connection and the access modes it supports
+func getProtocol(w http.ResponseWriter, r *http.Request) {
+	p := conn.Protocol()
+	v := struct {
+		Protocol    vogo.Protocol   `json:"protocol"`
+		AccessModes map[string]bool `json:"access_modes"`
+	}{Protocol: p, AccessModes: vogo.SupportedAccessModes(p)}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	e := json.NewEncoder(w)
+	e.SetIndent("", "    ")
+	e.Encode(v)
+}
+
+// loadFaultCatalog loads the fault-code catalogue and attaches it to the error history EventTypes
+func loadFaultCatalog(c *config, dpt *vogo.DataPointType) {
+	fn := c.Definitions.Faults
+	if fn == "" {
+		fn = filepath.Join(filepath.Dir(c.Definitions.EventTypes), "faultcodes.json")
+		if _, err := os.Stat(fn); err != nil {
+			return
+		}
+	}
+
+	f, err := os.Open(fn)
+	if err != nil {
+		log.Errorf("Error opening file: %s", err)
+		return
+	}
+	defer f.Close()
+
+	fc, err := vogo.LoadFaultCatalog(f)
+	if err != nil {
+		log.Errorf("Error loading fault-code catalogue %s: %s", fn, err)
+		return
+	}
+	dpt.EventTypes.SetFaultCatalog(fc)
+	log.Infof("Loaded %v fault codes from %s", len(fc), fn)
+}
+
+// loadTextResources loads all Textresource_xx.xml files, using xx as language
+func loadTextResources(c *config) {
+	dir := c.Definitions.TextResources
+	if dir == "" {
+		dir = filepath.Dir(c.Definitions.EventTypes)
+	}
+
+	trs := make(map[string]vogo.TextResources)
+	fns, _ := filepath.Glob(filepath.Join(dir, "Textresource_*.xml"))
+	for _, fn := range fns {
+		lang := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(fn), "Textresource_"), ".xml"))
+
+		f, err := os.Open(fn)
+		if err != nil {
+			log.Errorf("Error opening file: %s", err)
+			continue
+		}
+		tr, err := vogo.LoadTextResources(f)
+		f.Close()
+		if err != nil {
+			log.Errorf("Error loading text resources %s: %s", fn, err)
+			continue
+		}
+		trs[lang] = tr
+		log.Infof("Loaded %v texts for language %v from %s", len(tr), lang, fn)
+	}
+	textResources.Store(&trs)
+}
+
+// httpTextResources returns the text resources for the language requested via ?lang= or Accept-Language, nil if there are none
+func httpTextResources(r *http.Request) vogo.TextResources {
+	trs := textResources.Load()
+	if trs == nil {
+		return nil
+	}
+	langs := []string{r.URL.Query().Get("lang")}
+	for _, l := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		// Ignore quality values, the languages are listed by preference anyway
+		langs = append(langs, strings.TrimSpace(strings.Split(l, ";")[0]))
+	}
+
+	for _, l := range langs {
+		l = strings.ToLower(l)
+		if tr, ok := (*trs)[l]; ok {
+			return tr
+		}
+		// Try the primary language of tags like de-AT
+		if tr, ok := (*trs)[strings.Split(l, "-")[0]]; ok && l != "" {
+			return tr
+		}
+	}
+	return nil
+}
+
+// loadAliases loads the alias file and resolves the aliases for the DataPoint
+func loadAliases(c *config, dpt *vogo.DataPointType) {
+	fn := c.Definitions.Aliases
+	if fn == "" {
+		fn = filepath.Join(filepath.Dir(c.Definitions.EventTypes), "aliases.json")
+		if _, err := os.Stat(fn); err != nil {
+			return
+		}
+	}
+
+	f, err := os.Open(fn)
+	if err != nil {
+		log.Errorf("Error opening file: %s", err)
+		return
+	}
+	defer f.Close()
+
+	al, err := dpt.LoadAliases(f)
+	if err != nil {
+		log.Errorf("Error loading aliases %s: %s", fn, err)
+		return
+	}
+	log.Infof("Loaded %v aliases for DataPoint %v from %s", len(al), dpt.ID, fn)
 }
 
 // function for interactive retrieval of data
 func cliget(id string) (string, error) {
-	et, ok := conn.DataPoint.EventTypes[id]
+	et, ok := conn.DataPoint().EventType(id)
 	if !ok {
 		return "", fmt.Errorf("no such EventType %v", id)
 	}
@@ -291,18 +674,50 @@ func main() {
 			})
 		*/
 		flag.PrintDefaults()
+		fmt.Fprintf(flagOut, "\nCommands:\n")
+		fmt.Fprintf(flagOut, "  config check\n    \tvalidate the configuration given by -config and show the effective settings\n")
+		fmt.Fprintf(flagOut, "  identify\n    \tread the ident of the device given by -c (or take it from -ident) and list the matching DataPoints\n")
+		fmt.Fprintf(flagOut, "  defs compile\n    \tparse the -d and -e files into the definition bundle given by -b for faster startup\n")
+		fmt.Fprintf(flagOut, "  defs lint [DataPointID]\n    \tcheck the EventTypes of a DataPoint or all EventTypes of the -e file\n")
+		fmt.Fprintf(flagOut, "  defs export DataPointID [yaml|json]\n    \twrite the EventTypes of a DataPoint from the -d and -e files in the format used by -defs\n")
 	}
 
 	flag.Parse()
 
-	if *verbose == true {
-		log.SetLevel(log.DebugLevel)
-		log.SetFormatter(&log.TextFormatter{
-			FullTimestamp: true,
-		})
+	if flag.Arg(0) != "config" {
+		// "config check" reports problems with the configuration itself
+		err := loadConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = setLogLevel()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if flag.NArg() > 0 {
+		var err error
+		switch flag.Arg(0) {
+		case "defs":
+			err = defsCommand(flag.Args()[1:])
+		case "identify":
+			err = identifyCommand()
+		case "config":
+			err = configCommand(flag.Args()[1:])
+		default:
+			err = fmt.Errorf("unknown command %v", flag.Arg(0))
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	if *connTo == "" {
+	// The settings at startup, changes on reload are applied explicitly
+	cfg := conf()
+
+	if cfg.Connection == "" && !cfg.Offline {
 		log.Fatal("Need connection string in -c option")
 		os.Exit(1)
 	}
@@ -321,7 +736,6 @@ func main() {
 	done := make(chan os.Signal, 1)
 
 	signal.Notify(done,
-		syscall.SIGHUP,
 		syscall.SIGINT,
 		syscall.SIGTERM,
 		syscall.SIGQUIT)
@@ -346,76 +760,139 @@ func main() {
 		os.Exit(0)
 	}()
 
-	conn = vogo.NewDevice()
-	conn.Connect(*connTo)
-
-	conn.DataPoint = &vogo.DataPointType{}
-	dpt := conn.DataPoint
-	dpt.EventTypes = make(vogo.EventTypeList)
+	// SIGHUP reloads the definitions, it is handled once they have been loaded initially
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 
-	result := conn.RawCmd(getSysDeviceIdent)
-	if result.Err != nil {
-		return
+	conn = vogo.NewDevice()
+	if cfg.Cache.Duration != nil {
+		conn.CacheDuration = *cfg.Cache.Duration
 	}
-
-	var sysDeviceID [8]byte
-	copy(sysDeviceID[:], result.Body[:8])
-
-	xmlFile, err := os.Open(*dpFile)
+	wp, err := writePolicy(cfg)
 	if err != nil {
-		log.Errorf("Error opening file: %s", err)
-		return
+		log.Fatal(err)
+	}
+	if wp.ReadOnly {
+		log.Infof("Read-only mode, all writes are refused")
+	}
+	for _, r := range wp.Raw {
+		log.Warnf("Raw writes enabled for addresses %v", r)
+	}
+	conn.SetWritePolicy(wp)
+	if _, err := trustedProxies(cfg); err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Audit.File != "" {
+		audit, err = openAuditLog(cfg.Audit.File)
+		if err != nil {
+			log.Fatal(err)
+		}
+		conn.OnWrite = audit.record
+		log.Infof("Recording writes in audit log %s", cfg.Audit.File)
+	}
+	if cfg.Connection != "" {
+		err := conn.Connect(cfg.Connection)
+		if err != nil {
+			log.Errorf("Can not connect to %s: %s", cfg.Connection, err)
+			if !cfg.Offline {
+				return
+			}
+		}
 	}
 
-	err = vogo.FindDataPointType(xmlFile, sysDeviceID, dpt)
-	xmlFile.Close()
-	if err != nil {
-		log.Errorf(err.Error())
+	dpt := &vogo.DataPointType{EventTypes: make(vogo.EventTypeList)}
+
+	var sysDeviceID [8]byte
+	if cfg.Ident != "" {
+		sdi, err := vogo.ParseSysDeviceIdent(cfg.Ident)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sysDeviceID = sdi
+	} else if conn.Connected() {
+		result := conn.RawCmd(getSysDeviceIdent)
+		if result.Err != nil {
+			log.Errorf("Can not read device ident: %s", result.Err)
+			if !cfg.Offline {
+				return
+			}
+		} else {
+			copy(sysDeviceID[:], result.Body[:8])
+		}
+	}
+	if sysDeviceID == [8]byte{} && cfg.DataPoint == "" {
+		log.Errorf("Device ident unknown, use -dp or -ident to start without a device")
 		return
 	}
-	j := len(dpt.EventTypes)
 
-	xmlFile, err = os.Open(*etFile)
+	i, j, cs, err := loadDefinitions(cfg, sysDeviceID, cfg.DataPoint, dpt)
 	if err != nil {
-		log.Errorf("Error opening file: %s", err)
+		log.Errorf(err.Error())
 		return
 	}
-
-	i := vogo.FindEventTypes(xmlFile, &dpt.EventTypes)
-	xmlFile.Close()
 	if i == 0 {
 		log.Errorf("No EventType definitions found for this DataPoint %v\n", sysDeviceID[:6])
 		return
 	}
 
 	if i != j {
-		log.Infof("Attn: %v EventType definitions found, but %v announced in DataPoint %v definition", i, j, dpt.ID)
+		log.Warnf("Attn: %v EventType definitions found, but %v announced in DataPoint %v definition, see /datapoint/diagnostics or \"%s defs lint %v\"", i, j, dpt.ID, os.Args[0], dpt.ID)
 	} else {
 		log.Infof("All %v EventTypes found for DataPoint %v\n", i, dpt.ID)
 	}
 
-	var h *http.Server
+	loadFaultCatalog(cfg, dpt)
+	loadAliases(cfg, dpt)
+	loadTextResources(cfg)
+	conn.SetDataPoint(dpt)
+	candidates.Store(&cs)
+
+	go func() {
+		for range hup {
+			log.Infof("Reloading configuration and definitions")
+			err := reloadConfig()
+			if err != nil {
+				log.Errorf("Reload failed, keeping the previous configuration: %s", err)
+				continue
+			}
+			err = reloadDefinitions()
+			if err != nil {
+				log.Errorf("Reload failed, keeping the previous definitions: %s", err)
+			}
+		}
+	}()
+
 	var router *mux.Router
 
-	if *httpServe != "" {
+	if len(cfg.HTTP.Listen) > 0 {
 		router = mux.NewRouter()
 
 		router.HandleFunc("/eventtypes", getEventTypes).Methods("GET")
 		router.HandleFunc("/datapoint", getDataPoint).Methods("GET")
+		router.HandleFunc("/datapoint/diagnostics", getDiagnostics).Methods("GET")
+		router.HandleFunc("/datapoint/candidates", getCandidates).Methods("GET")
 		router.HandleFunc("/version", versionInfo).Methods("GET")
-		router.HandleFunc("/event/{id}", getEvent).Methods("GET")
-		router.HandleFunc("/event/{id}", setEvent).Methods("POST")
-		router.HandleFunc("/raw/{addr:0x[0-9a-fA-F]+|[0-9]+}", getRaw).Methods("GET")
-		router.HandleFunc("/raw/{addr:0x[0-9a-fA-F]+|[0-9]+}/{len:0x[0-9a-fA-F]+|[0-9]+}", getRaw).Methods("GET")
-		router.HandleFunc("/raw/{addr:0x[0-9a-fA-F]+|[0-9]+}", setRaw).Methods("POST")
+		router.HandleFunc("/event/{id}", linkRequired(getEvent)).Methods("GET")
+		router.HandleFunc("/event/{id}", linkRequired(setEvent)).Methods("POST")
+		router.HandleFunc("/event/{id}/{index:[0-9]+}", linkRequired(setEvent)).Methods("POST")
+		router.HandleFunc("/events", linkRequired(getEvents)).Methods("GET")
+		router.HandleFunc("/events", linkRequired(postEvents)).Methods("POST")
+		router.HandleFunc("/event/{id}/reset", linkRequired(resetEvent)).Methods("POST")
+		router.HandleFunc("/settings/diff", linkRequired(getSettingsDiff)).Methods("GET")
+		router.HandleFunc("/audit", getAudit).Methods("GET")
+		router.HandleFunc("/faults", linkRequired(getFaults)).Methods("GET")
+		router.HandleFunc("/protocol", getProtocol).Methods("GET")
+		router.HandleFunc("/raw/{addr:0x[0-9a-fA-F]+|[0-9]+}", linkRequired(getRaw)).Methods("GET")
+		router.HandleFunc("/raw/{addr:0x[0-9a-fA-F]+|[0-9]+}/{len:0x[0-9a-fA-F]+|[0-9]+}", linkRequired(getRaw)).Methods("GET")
+		router.HandleFunc("/raw/{addr:0x[0-9a-fA-F]+|[0-9]+}", linkRequired(setRaw)).Methods("POST")
 
 		var webHandler http.FileSystem
-		if *webRoot != "" {
-			if info, err := os.Stat(*webRoot); err == nil && info.IsDir() {
-				log.Infof("Serving web UI from %s", *webRoot)
-				webHandler = http.Dir(*webRoot)
+		if cfg.HTTP.WebRoot != "" {
+			if info, err := os.Stat(cfg.HTTP.WebRoot); err == nil && info.IsDir() {
+				log.Infof("Serving web UI from %s", cfg.HTTP.WebRoot)
+				webHandler = http.Dir(cfg.HTTP.WebRoot)
 			} else {
-				log.Warnf("Webroot %s not accessible, falling back to embedded files", *webRoot)
+				log.Warnf("Webroot %s not accessible, falling back to embedded files", cfg.HTTP.WebRoot)
 			}
 		}
 		if webHandler == nil {
@@ -424,29 +901,34 @@ func main() {
 		}
 		router.PathPrefix("/").Handler(http.FileServer(webHandler))
 
-		// accept :[portnum] as well as [portnum]
-		if i, err := strconv.Atoi(*httpServe); err == nil {
-			*httpServe = fmt.Sprintf(":%d", i)
-		}
-
-		s := strings.Split(*httpServe, ":")
-		if len(s) == 2 {
-			if port, err := strconv.Atoi(s[1]); err == nil {
+		for i, addr := range cfg.HTTP.Listen {
+			addr, port, err := normalizeListen(addr)
+			if err != nil {
+				log.Errorf("Can not start http server at %s: %s", addr, err)
+				continue
+			}
+			if i == 0 {
 				host, _ := os.Hostname()
-				if len(s[0]) > 0 {
-					host = s[0]
+				if h, _, _ := net.SplitHostPort(addr); h != "" {
+					host = h
 				}
-				instance := fmt.Sprintf("vogod_%s", conn.DataPoint.ID)
+				instance := fmt.Sprintf("vogod_%s", conn.DataPoint().ID)
 				go avahiPublish(instance, "_http._tcp", port)
 				log.Infof("Started avahi-publish on %s", host)
 			}
-		}
 
-		h = &http.Server{Addr: *httpServe, Handler: router}
-		go func() { log.Error(h.ListenAndServe()) }()
+			h := &http.Server{Addr: addr, Handler: router}
+			go func() { log.Error(h.ListenAndServe()) }()
+		}
 
+		if cfg.Connection == "" {
+			// Offline without a link, just serve the definitions
+			select {}
+		}
 		for {
-			<-conn.Done
+			if conn.Connected() {
+				<-conn.Done
+			}
 			<-time.After(12 * time.Second)
 			err := conn.Reconnect()
 			if err != nil {
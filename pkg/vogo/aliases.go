@@ -0,0 +1,85 @@
+package vogo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// aliasTargets holds the EventType IDs an alias may refer to, given as a single string or as a list of candidates
+type aliasTargets []string
+
+func (a *aliasTargets) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = aliasTargets{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(a))
+}
+
+// LoadAliases reads an alias file in JSON format and resolves the aliases for the given DataPoint.
+// Sections are keyed by DataPoint ID, the section "*" applies to all DataPoints. An alias may list several
+// EventType IDs, the first one present in the DataPoint is used. DataPoint specific sections take precedence, an alias
+// defined there is dropped if none of its EventTypes is present, even if "*" could resolve it:
+//
+//	{
+//	    "*": {"room_setpoint_circuit1": ["BedienRTSolltemperaturA1M1", "BedienRTSolltemperatur_A1M1"]},
+//	    "VScotHO1_72": {"outside_temperature": "Gemeinsame_Temperatur_ATS"}
+//	}
+//
+// The resolved aliases are also recorded in EventType.Aliases.
+func (dp *DataPointType) LoadAliases(r io.Reader) (EventTypeAliasList, error) {
+	var m map[string]map[string]aliasTargets
+
+	err := json.NewDecoder(r).Decode(&m)
+	if err != nil {
+		return nil, err
+	}
+
+	al := make(EventTypeAliasList)
+	for _, section := range []string{"*", dp.ID} {
+		for alias, ids := range m[section] {
+			if _, ok := dp.EventTypes[alias]; ok {
+				return nil, fmt.Errorf("alias %v is an EventType ID itself", alias)
+			}
+			// A DataPoint specific alias replaces the one of "*", even if it can't be resolved
+			delete(al, alias)
+			for _, id := range ids {
+				if et, ok := dp.EventTypes[id]; ok {
+					al[alias] = et
+					break
+				}
+			}
+			if _, ok := al[alias]; !ok && section == dp.ID {
+				log.Warnf("Alias %v refers to no EventType of DataPoint %v: %v", alias, dp.ID, strings.Join(ids, ", "))
+			}
+		}
+	}
+
+	for _, et := range dp.EventTypes {
+		et.Aliases = nil
+	}
+	for alias, et := range al {
+		et.Aliases = append(et.Aliases, alias)
+	}
+	for _, et := range al {
+		sort.Strings(et.Aliases)
+	}
+
+	dp.Aliases = al
+	return al, nil
+}
+
+// EventType returns the EventType with the given ID or alias
+func (dp *DataPointType) EventType(ID string) (et *EventType, ok bool) {
+	et, ok = dp.EventTypes[ID]
+	if !ok {
+		et, ok = dp.Aliases[ID]
+	}
+	return et, ok
+}
@@ -0,0 +1,44 @@
+package vogo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadAliases(t *testing.T) {
+	dp := &DataPointType{ID: "VScotHO1_72", EventTypes: EventTypeList{
+		"BedienRTSolltemperaturA1M1": &EventType{ID: "BedienRTSolltemperaturA1M1"},
+		"Gemeinsame_Temperatur_ATS":  &EventType{ID: "Gemeinsame_Temperatur_ATS"},
+		"Aussentemperatur":           &EventType{ID: "Aussentemperatur"},
+	}}
+	aliases := `{
+		"*": {
+			"room_setpoint": ["BedienRTSolltemperatur_A1M1", "BedienRTSolltemperaturA1M1"],
+			"outside_temperature": "Aussentemperatur",
+			"hot_water": "Warmwasser"
+		},
+		"VScotHO1_72": {"outside_temperature": "Gemeinsame_Temperatur_ATS", "room_setpoint": "Missing"},
+		"Other": {"hot_water": "Aussentemperatur"}
+	}`
+
+	al, err := dp.LoadAliases(strings.NewReader(aliases))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if et := al["outside_temperature"]; et == nil || et.ID != "Gemeinsame_Temperatur_ATS" {
+		t.Errorf("outside_temperature = %v, want the DataPoint specific EventType", et)
+	}
+	for _, alias := range []string{"room_setpoint", "hot_water"} {
+		if et, ok := al[alias]; ok {
+			t.Errorf("%v = %v, want it unresolved", alias, et.ID)
+		}
+	}
+	if a := dp.EventTypes["Aussentemperatur"].Aliases; len(a) != 0 {
+		t.Errorf("Aussentemperatur has aliases %v, want none", a)
+	}
+
+	_, err = dp.LoadAliases(strings.NewReader(`{"*": {"Aussentemperatur": "Gemeinsame_Temperatur_ATS"}}`))
+	if err == nil {
+		t.Errorf("alias shadowing an EventType ID did not fail")
+	}
+}
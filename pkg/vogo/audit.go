@@ -0,0 +1,97 @@
+package vogo
+
+import "time"
+
+// WriteOrigin tells who asked for a write, it is passed on to Device.OnWrite
+type WriteOrigin struct {
+	Client      string // Network address of the client
+	User        string // Authenticated user, if any
+	ClaimedUser string // User name given by the client, which could not be verified
+}
+
+// WriteRecord describes a single write to the device, successful or not. Writes refused before the device was read
+// carry no bytes.
+type WriteRecord struct {
+	Time        time.Time `json:"time"`
+	Client      string    `json:"client,omitempty"`
+	User        string    `json:"user,omitempty"`
+	ClaimedUser string    `json:"claimed_user,omitempty"` // Not verified, see WriteOrigin
+	Raw         bool      `json:"raw,omitempty"`          // Written with RawWrite, ID is empty then
+
+	ID      string      `json:"id,omitempty"`
+	Address AddressT    `json:"address"` // Address of the first byte written
+	Old     HexBytes    `json:"old,omitempty"`
+	New     HexBytes    `json:"new,omitempty"`
+	Before  interface{} `json:"before,omitempty"` // Decoded value of Old
+	After   interface{} `json:"after,omitempty"`  // Decoded value of New
+	Error   string      `json:"error,omitempty"`
+}
+
+// withPlan sets the address, bytes and values of the record from plan
+func (rec WriteRecord) withPlan(plan WritePlan) WriteRecord {
+	rec.ID, rec.Address = plan.ID, plan.Address
+	rec.Old, rec.New = plan.Old, plan.New
+	rec.Before, rec.After = plan.Before, plan.After
+	return rec
+}
+
+// origin returns the first WriteOrigin of the optional arguments of the write methods
+func origin(origins []WriteOrigin) WriteOrigin {
+	if len(origins) > 0 {
+		return origins[0]
+	}
+	return WriteOrigin{}
+}
+
+// plan describes writing the block b over old, which are nil if not known. If index is not negative, only the chunk
+// holding that element is described.
+func (et *EventType) plan(index int, old, b []byte) (plan WritePlan, err error) {
+	plan = WritePlan{ID: et.ID, Address: et.Address, Old: old, New: b}
+	if b == nil {
+		return plan, nil
+	}
+	if index >= 0 {
+		// Only the chunk holding the element is written
+		step := int(et.step())
+		plan.Address += AddressT(index * step)
+		plan.New = b[index*step : (index+1)*step]
+		if old != nil {
+			plan.Old = old[index*step : (index+1)*step]
+			plan.Before, _ = et.decodeIndex(&old, index)
+		}
+		plan.After, err = et.decodeIndex(&b, index)
+		return plan, err
+	}
+	if old != nil {
+		plan.Before, _ = et.decodeBlock(&old)
+	}
+	plan.After, err = et.decodeBlock(&b)
+	return plan, err
+}
+
+// recordWrite passes a write of an EventType to OnWrite, see plan for the arguments
+func (o *Device) recordWrite(origin WriteOrigin, et *EventType, index int, old, b []byte, err error) {
+	if o.OnWrite == nil {
+		return
+	}
+	plan, _ := et.plan(index, old, b)
+	o.record(origin, WriteRecord{}.withPlan(plan), err)
+}
+
+// record completes rec and passes it to OnWrite
+func (o *Device) record(origin WriteOrigin, rec WriteRecord, err error) {
+	if o.OnWrite == nil {
+		return
+	}
+	o.OnWrite(rec.Complete(origin, err))
+}
+
+// Complete sets the time, the origin and the error of err of the record
+func (rec WriteRecord) Complete(origin WriteOrigin, err error) WriteRecord {
+	rec.Time = time.Now()
+	rec.Client, rec.User, rec.ClaimedUser = origin.Client, origin.User, origin.ClaimedUser
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	return rec
+}
@@ -0,0 +1,24 @@
+package vogo
+
+import "testing"
+
+func TestRawWriteDeniedRecord(t *testing.T) {
+	var recs []WriteRecord
+	o := NewDevice()
+	o.OnWrite = func(rec WriteRecord) { recs = append(recs, rec) }
+
+	_, err := o.RawWrite(0x2306, []byte{0x15}, []byte{0x16}, WriteOrigin{Client: "192.0.2.1", ClaimedUser: "alice"})
+	if err == nil {
+		t.Fatalf("raw write without Raw ranges was not denied")
+	}
+	if len(recs) != 1 {
+		t.Fatalf("got %v records, want 1", len(recs))
+	}
+	rec := recs[0]
+	if !rec.Raw || rec.Address != 0x2306 || rec.Error == "" || rec.Client != "192.0.2.1" || rec.ClaimedUser != "alice" || rec.User != "" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	if rec.Old != nil || rec.New != nil {
+		t.Errorf("denied write carries bytes: old % X, new % X", rec.Old, rec.New)
+	}
+}
@@ -0,0 +1,205 @@
+package vogo
+
+import (
+	"encoding/gob"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// bundleVersion is increased whenever the layout of a DefinitionBundle changes
+const bundleVersion = 1
+
+// BundleSource records a file a DefinitionBundle was compiled from, to detect outdated bundles
+type BundleSource struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// bundleEventType is an EventType as found in the xml file, along with the result of its validation
+type bundleEventType struct {
+	XML xEventType
+	Err string
+}
+
+// DefinitionBundle holds the definitions of ecnDataPointType.xml and ecnEventType.xml in an indexed form,
+// which can be stored via Write and loaded much faster than parsing the xml files
+type DefinitionBundle struct {
+	Version    int
+	Sources    []BundleSource
+	DataPoints map[uint16][]xDataPointType // Indexed by sysDeviceGroupIdent<<8 | sysDeviceIdent
+	EventTypes map[string]bundleEventType  // Indexed by ID
+}
+
+func newBundleSource(fn string) (BundleSource, error) {
+	fi, err := os.Stat(fn)
+	if err != nil {
+		return BundleSource{}, err
+	}
+	return BundleSource{Path: fn, Size: fi.Size(), ModTime: fi.ModTime()}, nil
+}
+
+// CompileDefinitions parses the xml files for DataPoints and EventTypes into a DefinitionBundle
+func CompileDefinitions(dpFile, etFile string) (*DefinitionBundle, error) {
+	b := &DefinitionBundle{
+		Version:    bundleVersion,
+		DataPoints: make(map[uint16][]xDataPointType),
+		EventTypes: make(map[string]bundleEventType),
+	}
+
+	for _, fn := range []string{dpFile, etFile} {
+		src, err := newBundleSource(fn)
+		if err != nil {
+			return nil, err
+		}
+		b.Sources = append(b.Sources, src)
+	}
+
+	err := forEachXMLElement(dpFile, "DataPointType", func(decoder *xml.Decoder, se *xml.StartElement) {
+		var d xDataPointType
+		decoder.DecodeElement(&d, se)
+		if i, ok := dataPointIdent(d); ok {
+			b.DataPoints[i] = append(b.DataPoints[i], d)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = forEachXMLElement(etFile, "EventType", func(decoder *xml.Decoder, se *xml.StartElement) {
+		var et xEventType
+		decoder.DecodeElement(&et, se)
+
+		// Strip address off the name
+		et.ID = strings.Split(et.ID, "~0x")[0]
+
+		bet := bundleEventType{XML: et}
+		if _, err := validatexEventType(et); err != nil {
+			bet.Err = err.Error()
+		}
+		b.EventTypes[et.ID] = bet
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// forEachXMLElement calls f for every element with the given name in an xml file
+func forEachXMLElement(fn string, name string, f func(decoder *xml.Decoder, se *xml.StartElement)) error {
+	xmlFile, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer xmlFile.Close()
+
+	decoder := xml.NewDecoder(xmlFile)
+	for {
+		t, _ := decoder.Token()
+		if t == nil {
+			break
+		}
+		if se, ok := t.(xml.StartElement); ok && se.Name.Local == name {
+			f(decoder, &se)
+		}
+	}
+	return nil
+}
+
+// ReadDefinitionBundle reads a DefinitionBundle previously stored via Write
+func ReadDefinitionBundle(r io.Reader) (*DefinitionBundle, error) {
+	var b DefinitionBundle
+	err := gob.NewDecoder(r).Decode(&b)
+	if err != nil {
+		return nil, err
+	}
+	if b.Version != bundleVersion {
+		return nil, fmt.Errorf("definition bundle has version %v, expected %v", b.Version, bundleVersion)
+	}
+	return &b, nil
+}
+
+// Write stores the DefinitionBundle
+func (b *DefinitionBundle) Write(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(b)
+}
+
+// UpToDate checks if the source files of the DefinitionBundle are unchanged. The given files must be the sources of the bundle.
+func (b *DefinitionBundle) UpToDate(files ...string) bool {
+	if len(files) != len(b.Sources) {
+		return false
+	}
+	for i, fn := range files {
+		src, err := newBundleSource(fn)
+		if err != nil {
+			return false
+		}
+		if src.Path != b.Sources[i].Path || src.Size != b.Sources[i].Size || !src.ModTime.Equal(b.Sources[i].ModTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// Invalid returns the IDs of EventTypes which failed validation, along with the reason
+func (b *DefinitionBundle) Invalid() map[string]string {
+	r := make(map[string]string)
+	for id, bet := range b.EventTypes {
+		if bet.Err != "" {
+			r[id] = bet.Err
+		}
+	}
+	return r
+}
+
+// FindDataPointType works like the package level FindDataPointType, using the bundle instead of xml
+func (b *DefinitionBundle) FindDataPointType(sysDeviceIdent [8]byte, dpt *DataPointType) error {
+	var dp xDataPointType
+	for _, d := range b.DataPoints[uint16(sysDeviceIdent[0])<<8|uint16(sysDeviceIdent[1])] {
+		if betterDataPointMatch(d, dp, sysDeviceIdent) {
+			dp = d
+		}
+	}
+	return setDataPointType(dp, sysDeviceIdent, dpt)
+}
+
+// FindDataPointTypeByID works like the package level FindDataPointTypeByID, using the bundle instead of xml
+func (b *DefinitionBundle) FindDataPointTypeByID(ID string, dpt *DataPointType) error {
+	for i, dps := range b.DataPoints {
+		for _, d := range dps {
+			if d.ID == ID {
+				return setDataPointType(d, [8]byte{byte(i >> 8), byte(i)}, dpt)
+			}
+		}
+	}
+	return ErrNotFound
+}
+
+// FindEventTypes works like the package level FindEventTypes, using the bundle instead of xml.
+// EventTypes are validated again, as the result may depend on codecs registered via RegisterCodec.
+func (b *DefinitionBundle) FindEventTypes(etl *EventTypeList) int {
+	found := 0
+	for id := range *etl {
+		bet, ok := b.EventTypes[id]
+		if !ok {
+			continue
+		}
+		vet, err := validatexEventType(bet.XML)
+		if err != nil {
+			log.Debugf(err.Error())
+			// Keep a placeholder to report the problems
+			(*etl)[id] = invalidEventType(vet, bet.XML)
+			continue
+		}
+		(*etl)[id] = &vet
+		found++
+	}
+	return found
+}
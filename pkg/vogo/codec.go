@@ -1,9 +1,13 @@
 package vogo
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,31 +22,205 @@ type Codec interface {
 	Encode(et *EventType, b *[]byte, v interface{}) (err error)
 }
 
-type nopCodec struct{}
+type codecKey struct {
+	conversion  string
+	mappingType uint8
+}
+
+var codecRegistry = make(map[codecKey]Codec)
+var codecRegistryLock sync.RWMutex
+
+// RegisterCodec registers a Codec for EventTypes with the given Conversion and MappingType.
+// Registered codecs are consulted when definitions are loaded and take precedence over the built-in ones,
+// so they can be used to add conversions vogo does not know about as well as to override built-in conversions.
+// Registering a nil Codec removes a previous registration.
+func RegisterCodec(conversion string, mappingType uint8, c Codec) {
+	codecRegistryLock.Lock()
+	defer codecRegistryLock.Unlock()
+
+	k := codecKey{conversion, mappingType}
+	if c == nil {
+		delete(codecRegistry, k)
+		return
+	}
+	codecRegistry[k] = c
+}
+
+// LookupCodec returns the Codec registered via RegisterCodec for the given Conversion and MappingType
+func LookupCodec(conversion string, mappingType uint8) (c Codec, ok bool) {
+	codecRegistryLock.RLock()
+	defer codecRegistryLock.RUnlock()
+
+	c, ok = codecRegistry[codecKey{conversion, mappingType}]
+	return c, ok
+}
+
+// BlockCodec is implemented by codecs which decode and encode all elements of a block-factored EventType at once,
+// like a switching schedule for a whole week. Other codecs are applied to every element of the block.
+type BlockCodec interface {
+	Codec
+	WholeBlock() bool
+}
+
+func isBlockCodec(c Codec) bool {
+	bc, ok := c.(BlockCodec)
+	return ok && bc.WholeBlock()
+}
+
+// RelativeCodec is implemented by codecs which may encode a value relative to the current data, like a correction of
+// a counter. The data is then read from the device prior to writing, bypassing the cache.
+type RelativeCodec interface {
+	Codec
+	Relative() bool
+}
+
+func isRelativeCodec(c Codec) bool {
+	rc, ok := c.(RelativeCodec)
+	return ok && rc.Relative()
+}
 
-func (nopCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) { return (*b), nil }
-func (nopCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) { return nil }
-func (codec nopCodec) MarshalJSON() ([]byte, error) {
+// Elements returns the number of elements the data of an EventType is decoded into.
+// It is 1 for EventTypes without a BlockFactor or with a BlockCodec.
+func (et *EventType) Elements() int {
+	if et.BlockFactor <= 1 || isBlockCodec(et.Codec) || et.BlockLength%et.BlockFactor != 0 {
+		return 1
+	}
+	return int(et.BlockFactor)
+}
+
+// element returns a view of the EventType describing its i-th element and the data of that element
+func (et *EventType) element(b []byte, i int) (*EventType, []byte, error) {
+	step := int(et.BlockLength / et.BlockFactor)
+	if len(b) < (i+1)*step {
+		return nil, nil, fmt.Errorf("could not access element %v of EventType %v: data length does not match BlockLength", i, et.ID)
+	}
+
+	e := *et
+	e.BlockLength = uint8(step)
+	e.BlockFactor = 0
+	// BytePosition and BitPosition may count from the start of the whole block
+	e.BytePosition %= uint8(step)
+	e.BitPosition = uint8(int(et.BitPosition) % (step * 8))
+	return &e, b[i*step : (i+1)*step], nil
+}
+
+// decodeBlock decodes the data of an EventType, returning a []interface{} with one value per element if it is block-factored
+func (et *EventType) decodeBlock(b *[]byte) (v interface{}, err error) {
+	n := et.Elements()
+	if n == 1 {
+		return et.Codec.Decode(et, b)
+	}
+
+	values := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		values[i], err = et.decodeIndex(b, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// decodeIndex decodes the i-th element of a block-factored EventType
+func (et *EventType) decodeIndex(b *[]byte, i int) (v interface{}, err error) {
+	e, c, err := et.element(*b, i)
+	if err != nil {
+		return nil, err
+	}
+	return et.Codec.Decode(e, &c)
+}
+
+// encodeBlock encodes v into the data of an EventType. Block-factored EventTypes expect a slice with one value per element.
+func (et *EventType) encodeBlock(b *[]byte, v interface{}) (err error) {
+	n := et.Elements()
+	if n == 1 {
+		return et.Codec.Encode(et, b, v)
+	}
+
+	values, ok := v.([]interface{})
+	if !ok || len(values) != n {
+		return fmt.Errorf("EventType %v expects an array of %v values", et.ID, n)
+	}
+	for i := 0; i < n; i++ {
+		err = et.encodeIndex(b, i, values[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeIndex encodes v into the i-th element of a block-factored EventType
+func (et *EventType) encodeIndex(b *[]byte, i int, v interface{}) (err error) {
+	e, c, err := et.element(*b, i)
+	if err != nil {
+		return err
+	}
+	err = et.Codec.Encode(e, &c, v)
+	if err != nil {
+		return fmt.Errorf("element %v: %w", i, err)
+	}
+	copy((*b)[i*len(c):], c)
+	return nil
+}
+
+// decodeDefault decodes the ALZ (factory default) of an EventType by encoding it into an empty element and decoding it again,
+// so the default has the same type and precision as values read from the device
+func (et *EventType) decodeDefault() (v interface{}, err error) {
+	s := strings.TrimSpace(et.ALZ)
+	if s == "" || et.Codec == nil || isBlockCodec(et.Codec) {
+		return nil, nil
+	}
+
+	var alz interface{} = s
+	if f, errF := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64); errF == nil {
+		alz = f
+	}
+
+	e := et
+	if et.Elements() > 1 {
+		e, _, err = et.element(make([]byte, et.BlockLength), 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	b := make([]byte, e.BlockLength)
+	err = e.Codec.Encode(e, &b, alz)
+	if err != nil {
+		return nil, fmt.Errorf("can't encode ALZ '%v' of EventType %v: %w", et.ALZ, et.ID, err)
+	}
+	return e.Codec.Decode(e, &b)
+}
+
+// NopCodec passes the raw data through on Decode and does not touch the data on Encode
+type NopCodec struct{}
+
+func (NopCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) { return (*b), nil }
+func (NopCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) { return nil }
+func (NopCodec) WholeBlock() bool                                           { return true }
+func (codec NopCodec) MarshalJSON() ([]byte, error) {
 	t := strings.Split(reflect.TypeOf(codec).String(), ".")
 	return []byte(fmt.Sprintf("\"%s\"", t[len(t)-1])), nil
 }
 
-type valueListCodec struct{}
+// ValueListCodec handles numeric keys of a ValueList, which are stored as plain integers or bit fields
+type ValueListCodec struct{}
 
-func (valueListCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
-	if et.BitLength > 8 {
-		return nil, fmt.Errorf("valueListCodec: can not handle BitLength > 8")
+func (ValueListCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
+	if et.BitLength > 16 {
+		return nil, fmt.Errorf("ValueListCodec: can not handle BitLength > 16")
 	}
 
 	// While there are few EventTypes with ByteLength of 3, 4, 6, it seems sufficient to treat the ValueList as uint16
 	var d uint16
 
 	if et.BitLength > 0 {
-		// BytePosition seems not always correct in the data from Vit*soft, so calculate
-		bytepos := et.BitPosition / 8
-		// bitpos in bytepos' byte
-		bitpos := et.BitPosition % 8
-		d = uint16(((*b)[bytepos] >> bitpos) & ((1 << et.BitLength) - 1))
+		// BytePosition seems not always correct in the data from Vit*soft, so BitPosition counts from the block start
+		bits, err := getBits(*b, uint(et.BitPosition), uint(et.BitLength))
+		if err != nil {
+			return nil, fmt.Errorf("ValueListCodec: %v", err)
+		}
+		d = uint16(bits)
 	} else {
 		if et.ByteLength == 1 {
 			d = uint16((*b)[et.BytePosition])
@@ -52,9 +230,9 @@ func (valueListCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error
 	}
 	return d, nil
 }
-func (valueListCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) {
-	if et.BitLength > 8 {
-		return fmt.Errorf("valueListCodec: can not handle BitLength > 8")
+func (ValueListCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) {
+	if et.BitLength > 16 {
+		return fmt.Errorf("ValueListCodec: can not handle BitLength > 16")
 	}
 
 	// While there are few EventTypes with ByteLength of 3, 4, 6, it seems sufficient to treat the ValueList as uint16
@@ -90,13 +268,14 @@ func (valueListCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error
 	}
 
 	if et.BitLength > 0 {
-		// BytePosition seems not always correct in the data from Vit*soft, so calculate
-		bytepos := et.BitPosition / 8
-		// bitpos in bytepos' byte
-		bitpos := et.BitPosition % 8
-		e := (*b)[bytepos] &^ (((1 << et.BitLength) - 1) << bitpos)
-		f := byte(d) << bitpos
-		(*b)[bytepos] = e | f
+		// BytePosition seems not always correct in the data from Vit*soft, so BitPosition counts from the block start
+		if d >= (1 << et.BitLength) {
+			return fmt.Errorf("ValueListCodec: %v does not fit into %v bits", d, et.BitLength)
+		}
+		err = setBits(*b, uint(et.BitPosition), uint(et.BitLength), uint64(d))
+		if err != nil {
+			return fmt.Errorf("ValueListCodec: %v", err)
+		}
 	} else {
 		if et.ByteLength == 1 {
 			(*b)[et.BytePosition] = byte(d)
@@ -109,39 +288,51 @@ func (valueListCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error
 	return nil
 }
 
-func (codec valueListCodec) MarshalJSON() ([]byte, error) {
+func (codec ValueListCodec) MarshalJSON() ([]byte, error) {
 	t := strings.Split(reflect.TypeOf(codec).String(), ".")
 	return []byte(fmt.Sprintf("\"%s\"", t[len(t)-1])), nil
 }
 
-type divMulOffsetCodec struct{}
+// DivMulOffsetCodec handles numeric values which are scaled by ConversionFactor and shifted by ConversionOffset
+type DivMulOffsetCodec struct{}
 
-func (divMulOffsetCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
-	if len((*b)) < (int(et.BytePosition) + int(et.ByteLength)) {
-		return nil, fmt.Errorf("dateDivMulOffsetCodec: Data length mismatch")
+// bitFieldPosition returns the position of the bit field of a DivMulOffsetCodec for getBits and setBits. Nibbles of a
+// single byte are addressed like in the data from Vit*soft: BitPosition 0 is the upper and 4 the lower nibble of the
+// byte at BytePosition. Other bit fields count from the least significant bit of the block like in ValueListCodec.
+func (et *EventType) bitFieldPosition() uint {
+	if et.ByteLength == 1 && et.BitLength == 4 && (et.BitPosition == 0 || et.BitPosition == 4) {
+		return uint(et.BytePosition)*8 + 4 - uint(et.BitPosition)
 	}
+	return uint(et.BitPosition)
+}
 
-	if (et.BitLength > 0) && !((et.ByteLength == 1) && (et.BitLength == 4)) {
-		return nil, fmt.Errorf("dateDivMulOffsetCodec: Can not handle arbitrary BitLength")
-	}
+func (DivMulOffsetCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
 	var f float32
 
+	if et.BitLength > 0 {
+		d, err := getBits(*b, et.bitFieldPosition(), uint(et.BitLength))
+		if err != nil {
+			return nil, fmt.Errorf("DivMulOffsetCodec: %v", err)
+		}
+		if et.signed() {
+			f = float32(signExtend(d, uint(et.BitLength)))
+		} else {
+			f = float32(d)
+		}
+		return ((f * et.ConversionFactor) + et.ConversionOffset), nil
+	}
+
+	if len((*b)) < (int(et.BytePosition) + int(et.ByteLength)) {
+		return nil, fmt.Errorf("DivMulOffsetCodec: Data length mismatch")
+	}
+
 	c := (*b)[et.BytePosition:(int(et.BytePosition) + int(et.ByteLength))]
 	switch et.ByteLength {
 	case 1:
 		if et.Parameter == "SByte" || et.Parameter == "SInt" {
 			f = float32(int8(c[0]))
 		} else {
-			d := uint8(c[0])
-			if et.BitLength == 4 {
-				// Handle nibbles
-				if et.BitPosition == 0 {
-					d = d >> 4
-				} else if et.BitPosition == 4 {
-					d = d & 0xf
-				}
-			}
-			f = float32(d)
+			f = float32(uint8(c[0]))
 		}
 	case 2:
 		h := 1
@@ -168,79 +359,53 @@ func (divMulOffsetCodec) Decode(et *EventType, b *[]byte) (v interface{}, err er
 			f = float32(v.(int32))
 		}
 	default:
-		return nil, fmt.Errorf("dateDivMulOffsetCodec: can not convert ByteLength %v", et.ByteLength)
+		return nil, fmt.Errorf("DivMulOffsetCodec: can not convert ByteLength %v", et.ByteLength)
 	}
 
 	return ((f * et.ConversionFactor) + et.ConversionOffset), nil
 }
 
-func (divMulOffsetCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) {
-	if len((*b)) < (int(et.BytePosition) + int(et.ByteLength)) {
-		return fmt.Errorf("dateDivMulOffsetCodec: Data length mismatch")
+func (DivMulOffsetCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) {
+	if et.BitLength == 0 && len((*b)) < (int(et.BytePosition)+int(et.ByteLength)) {
+		return fmt.Errorf("DivMulOffsetCodec: Data length mismatch")
 	}
 
-	if (et.BitLength > 0) && !((et.ByteLength == 1) && (et.BitLength == 4)) {
-		return fmt.Errorf("dateDivMulOffsetCodec: Can not handle arbitrary BitLength")
+	f, err := float32Value(v)
+	if err != nil {
+		return err
 	}
 
-	var f float32
-
-	switch v.(type) {
-	case float32:
-		f = v.(float32)
-	case float64:
-		f = float32(v.(float64))
-	case int:
-		f = float32(v.(int))
-	case int8:
-		f = float32(v.(int8))
-	case int16:
-		f = float32(v.(int16))
-	case int32:
-		f = float32(v.(int32))
-	case int64:
-		f = float32(v.(int64))
-	case uint:
-		f = float32(v.(uint))
-	case uint8:
-		f = float32(v.(uint8))
-	case uint16:
-		f = float32(v.(uint16))
-	case uint32:
-		f = float32(v.(uint32))
-	case uint64:
-		f = float32(v.(uint64))
-	default:
-		return fmt.Errorf("value must be a basic numeric type")
+	err = et.CheckValue(f)
+	if err != nil {
+		return err
 	}
 
-	if et.LowerBorder != et.UpperBorder {
-		if f < et.LowerBorder {
-			f = et.LowerBorder
+	// Round instead of truncating, as e.g. 21.5/0.1 would result in 214.99998
+	f = float32(math.Round(float64((f - et.ConversionOffset) / et.ConversionFactor)))
+
+	if et.BitLength > 0 {
+		n := uint(et.BitLength)
+		d := int64(f)
+		if et.signed() {
+			if n < 64 && (d < -(1<<(n-1)) || d >= (1<<(n-1))) {
+				return fmt.Errorf("DivMulOffsetCodec: %v does not fit into a signed %v bit field", d, n)
+			}
+		} else if d < 0 || (n < 64 && d >= (1<<n)) {
+			return fmt.Errorf("DivMulOffsetCodec: %v does not fit into an unsigned %v bit field", d, n)
 		}
-		if f > et.UpperBorder {
-			f = et.UpperBorder
+		err = setBits(*b, et.bitFieldPosition(), n, uint64(d))
+		if err != nil {
+			return fmt.Errorf("DivMulOffsetCodec: %v", err)
 		}
+		return nil
 	}
 
-	f = (f - et.ConversionOffset) / et.ConversionFactor
-
 	switch et.ByteLength {
 	case 1:
 		if et.Parameter == "SByte" || et.Parameter == "SInt" {
 			(*b)[et.BytePosition] = byte(int8(f))
 		} else {
-			d := uint8(f)
-			if et.BitLength == 4 {
-				// Handle nibbles
-				if et.BitPosition == 0 {
-					(*b)[et.BytePosition] = ((*b)[et.BytePosition] & 0xf) | (d << 4)
-				} else if et.BitPosition == 4 {
-					(*b)[et.BytePosition] = ((*b)[et.BytePosition] & 0xf0) | d
-				}
-			} else {
-				(*b)[et.BytePosition] = byte(d)
-			}
+			(*b)[et.BytePosition] = byte(uint8(f))
 		}
 	case 2:
 		if et.Parameter == "SIntHighByteFirst" {
@@ -281,19 +446,20 @@ func (divMulOffsetCodec) Encode(et *EventType, b *[]byte, v interface{}) (err er
 			(*b)[et.BytePosition+3] = byte((d >> 24) & 0xff)
 		}
 	default:
-		return fmt.Errorf("dateDivMulOffsetCodec: can not convert ByteLength %v", et.ByteLength)
+		return fmt.Errorf("DivMulOffsetCodec: can not convert ByteLength %v", et.ByteLength)
 	}
 
 	//return ((f * et.ConversionFactor) + et.ConversionOffset), nil
 	return nil
 }
 
-func (codec divMulOffsetCodec) MarshalJSON() ([]byte, error) {
+func (codec DivMulOffsetCodec) MarshalJSON() ([]byte, error) {
 	t := strings.Split(reflect.TypeOf(codec).String(), ".")
 	return []byte(fmt.Sprintf("\"%s\"", t[len(t)-1])), nil
 }
 
-type dateTimeBCDCodec struct{}
+// DateTimeBCDCodec handles date and time values stored as 8 BCD encoded bytes
+type DateTimeBCDCodec struct{}
 
 func decodeBCDDate(c []byte) (t time.Time, err error) {
 	if len(c) < 4 {
@@ -306,7 +472,7 @@ func decodeBCDDate(c []byte) (t time.Time, err error) {
 	return time.Date(fromBCD(c[0])*100+fromBCD(c[1]), time.Month(fromBCD(c[2])), fromBCD(c[3]), fromBCD(c[5]), fromBCD(c[6]), fromBCD(c[7]), 0, time.Local), nil
 }
 
-func (dateTimeBCDCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
+func (DateTimeBCDCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
 	if len(*b) < int(et.BlockLength) {
 		return nil, fmt.Errorf("could not decode: data length does not match BlockLength")
 	}
@@ -315,21 +481,23 @@ func (dateTimeBCDCodec) Decode(et *EventType, b *[]byte) (v interface{}, err err
 	return decodeBCDDate(c)
 }
 
-func (dateTimeBCDCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) {
+func (DateTimeBCDCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) {
 	var t time.Time
 
 	if len(*b) < int(et.BytePosition)+8 {
 		return fmt.Errorf("could not encode: data length does not fit")
 	}
 
-	switch v.(type) {
+	switch v := v.(type) {
 	case time.Time:
-		t = v.(time.Time)
-	default:
-		t, err = time.Parse(time.RFC3339, v.(string))
+		t = v
+	case string:
+		t, err = time.Parse(time.RFC3339, v)
 		if err != nil {
 			return fmt.Errorf("time parse error: need time.Time type or a parseable string")
 		}
+	default:
+		return fmt.Errorf("value must be a time.Time or a string in RFC 3339 format")
 	}
 
 	if t.IsZero() {
@@ -353,14 +521,15 @@ func (dateTimeBCDCodec) Encode(et *EventType, b *[]byte, v interface{}) (err err
 	return nil
 }
 
-func (codec dateTimeBCDCodec) MarshalJSON() ([]byte, error) {
+func (codec DateTimeBCDCodec) MarshalJSON() ([]byte, error) {
 	t := strings.Split(reflect.TypeOf(codec).String(), ".")
 	return []byte(fmt.Sprintf("\"%s\"", t[len(t)-1])), nil
 }
 
-type dateBCDCodec struct{}
+// DateBCDCodec handles date values stored as BCD encoded bytes
+type DateBCDCodec struct{}
 
-func (dateBCDCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
+func (DateBCDCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
 	if len(*b) < int(et.BlockLength) {
 		return nil, fmt.Errorf("could not decode: data length does not match BlockLength")
 	}
@@ -369,21 +538,23 @@ func (dateBCDCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error)
 	return decodeBCDDate(c)
 }
 
-func (dateBCDCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) {
+func (DateBCDCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) {
 	var t time.Time
 
 	if len(*b) < int(et.BytePosition)+4 {
 		return fmt.Errorf("could not encode: data length does not fit")
 	}
 
-	switch v.(type) {
+	switch v := v.(type) {
 	case time.Time:
-		t = v.(time.Time)
-	default:
-		t, err = time.Parse(time.RFC3339, v.(string))
+		t = v
+	case string:
+		t, err = time.Parse(time.RFC3339, v)
 		if err != nil {
 			return fmt.Errorf("time parse error: need time.Time type or a parseable string")
 		}
+	default:
+		return fmt.Errorf("value must be a time.Time or a string in RFC 3339 format")
 	}
 
 	if t.IsZero() {
@@ -415,68 +586,208 @@ func (dateBCDCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error)
 	return nil
 }
 
-func (codec dateBCDCodec) MarshalJSON() ([]byte, error) {
+func (codec DateBCDCodec) MarshalJSON() ([]byte, error) {
 	t := strings.Split(reflect.TypeOf(codec).String(), ".")
 	return []byte(fmt.Sprintf("\"%s\"", t[len(t)-1])), nil
 }
 
-type sec2DurationCodec struct{}
+// DurationValue is the decoded value of a counter of seconds, like burner hours or a remaining time.
+// Unit is the unit the counter is presented in (time.Hour or time.Minute).
+type DurationValue struct {
+	Seconds uint64
+	Unit    time.Duration
+}
+
+// Duration returns the counter as time.Duration
+func (d DurationValue) Duration() time.Duration {
+	return time.Duration(d.Seconds) * time.Second
+}
+
+// Value returns the counter in its Unit
+func (d DurationValue) Value() float64 {
+	return float64(d.Seconds) / d.Unit.Seconds()
+}
+
+func (d DurationValue) String() string {
+	return d.Duration().String()
+}
 
-func (sec2DurationCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
-	var t time.Duration
-	var secs uint
+func (d DurationValue) MarshalJSON() ([]byte, error) {
+	unit := "hours"
+	if d.Unit == time.Minute {
+		unit = "minutes"
+	}
+	return []byte(fmt.Sprintf("{\"seconds\": %d, \"%s\": %s, \"duration\": \"%s\"}", d.Seconds, unit, strconv.FormatFloat(d.Value(), 'f', -1, 64), d)), nil
+}
 
+// decodeSeconds reads a little endian counter of seconds
+func decodeSeconds(et *EventType, b *[]byte) (secs uint64, err error) {
 	if len((*b)) < (int(et.BytePosition) + int(et.ByteLength)) {
-		return nil, fmt.Errorf("sec2DurationCodec: Data length mismatch")
+		return 0, fmt.Errorf("could not decode: data length mismatch")
+	}
+	if et.ByteLength > 8 {
+		return 0, fmt.Errorf("could not decode: can not handle ByteLength %v", et.ByteLength)
 	}
 
-	secs = 0
 	for i := int(et.ByteLength) - 1; i >= 0; i-- {
 		secs = secs << 8
-		secs += uint((*b)[int(et.BytePosition)+i])
+		secs += uint64((*b)[int(et.BytePosition)+i])
 	}
-
-	t = time.Duration(secs) * time.Second
-
-	return t.String(), nil
+	return secs, nil
 }
-func (sec2DurationCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) {
-	var t time.Duration
-	var secs uint
 
+// encodeSeconds writes a little endian counter of seconds
+func encodeSeconds(et *EventType, b *[]byte, secs int64) (err error) {
 	if len((*b)) < (int(et.BytePosition) + int(et.ByteLength)) {
-		return fmt.Errorf("sec2DurationCodec: Data length mismatch")
+		return fmt.Errorf("could not encode: data length mismatch")
+	}
+	if secs < 0 {
+		return fmt.Errorf("could not encode: negative value %vs", secs)
+	}
+	if et.ByteLength < 8 && uint64(secs) >= (1<<(8*uint(et.ByteLength))) {
+		return fmt.Errorf("could not encode: %vs does not fit into %v bytes", secs, et.ByteLength)
 	}
 
-	switch v.(type) {
+	for i := 0; i < int(et.ByteLength); i++ {
+		(*b)[int(et.BytePosition)+i] = byte(secs & 0xff)
+		secs = secs >> 8
+	}
+	return nil
+}
+
+// durationSeconds converts a value to seconds: a DurationValue, a time.Duration, a string parseable by
+// time.ParseDuration, an object like {"seconds": 0}, {"minutes": 30} or {"hours": 1000} as decoded from JSON,
+// or a number taken in the given unit.
+func durationSeconds(v interface{}, unit time.Duration) (secs int64, err error) {
+	switch v := v.(type) {
+	case DurationValue:
+		return int64(v.Seconds), nil
 	case time.Duration:
-		t = v.(time.Duration)
+		return int64(v.Seconds()), nil
 	case string:
-		var err error
-		t, err = time.ParseDuration(v.(string))
+		t, err := time.ParseDuration(v)
 		if err != nil {
-			return err
+			return 0, err
+		}
+		return int64(t.Seconds()), nil
+	case map[string]interface{}:
+		for _, u := range []struct {
+			key  string
+			unit time.Duration
+		}{{"seconds", time.Second}, {"minutes", time.Minute}, {"hours", time.Hour}} {
+			if n, ok := v[u.key]; ok {
+				f, err := float64Value(n)
+				if err != nil {
+					return 0, err
+				}
+				return int64(math.Round(f * u.unit.Seconds())), nil
+			}
 		}
+		return 0, fmt.Errorf("object must contain one of seconds, minutes or hours")
 	default:
-		return fmt.Errorf("value must be a time.Duration type or a string that is parseable by time.ParseDuration(string)")
+		f, err := float64Value(v)
+		if err != nil {
+			return 0, fmt.Errorf("value must be a number, a time.Duration, a string that is parseable by time.ParseDuration(string) or an object with seconds, minutes or hours")
+		}
+		return int64(math.Round(f * unit.Seconds())), nil
 	}
+}
 
-	secs = uint(t.Seconds())
-	for i := 0; i < int(et.ByteLength); i++ {
-		(*b)[int(et.BytePosition)+i] = byte(secs & 0xff)
-		secs = secs >> 8
+// Sec2HourCodec handles counters of seconds, which are presented in hours
+type Sec2HourCodec struct{}
+
+func (Sec2HourCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
+	secs, err := decodeSeconds(et, b)
+	if err != nil {
+		return nil, err
 	}
+	return DurationValue{Seconds: secs, Unit: time.Hour}, nil
+}
 
-	return nil
+func (Sec2HourCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) {
+	secs, err := durationSeconds(v, time.Hour)
+	if err != nil {
+		return err
+	}
+	return encodeSeconds(et, b, secs)
+}
+
+func (codec Sec2HourCodec) MarshalJSON() ([]byte, error) {
+	t := strings.Split(reflect.TypeOf(codec).String(), ".")
+	return []byte(fmt.Sprintf("\"%s\"", t[len(t)-1])), nil
+}
+
+// Sec2MinuteCodec handles counters of seconds, which are presented in minutes
+type Sec2MinuteCodec struct{}
+
+func (Sec2MinuteCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
+	secs, err := decodeSeconds(et, b)
+	if err != nil {
+		return nil, err
+	}
+	return DurationValue{Seconds: secs, Unit: time.Minute}, nil
+}
+
+func (Sec2MinuteCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) {
+	secs, err := durationSeconds(v, time.Minute)
+	if err != nil {
+		return err
+	}
+	return encodeSeconds(et, b, secs)
+}
+
+func (codec Sec2MinuteCodec) MarshalJSON() ([]byte, error) {
+	t := strings.Split(reflect.TypeOf(codec).String(), ".")
+	return []byte(fmt.Sprintf("\"%s\"", t[len(t)-1])), nil
+}
+
+// HourDiffSec2HourCodec handles counters of seconds like burner hours, which are presented in hours.
+// Values are encoded as the new counter like with Sec2HourCodec. To correct the counter instead, encode an object
+// like {"add": -1.5} with a difference in hours or as duration string, which is added to the current counter.
+type HourDiffSec2HourCodec struct{}
+
+func (HourDiffSec2HourCodec) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
+	secs, err := decodeSeconds(et, b)
+	if err != nil {
+		return nil, err
+	}
+	return DurationValue{Seconds: secs, Unit: time.Hour}, nil
+}
+
+func (HourDiffSec2HourCodec) Encode(et *EventType, b *[]byte, v interface{}) (err error) {
+	if m, ok := v.(map[string]interface{}); ok {
+		if diff, ok := m["add"]; ok {
+			if len(m) > 1 {
+				return fmt.Errorf("object with add must not contain other keys")
+			}
+			secs, err := durationSeconds(diff, time.Hour)
+			if err != nil {
+				return err
+			}
+			cur, err := decodeSeconds(et, b)
+			if err != nil {
+				return err
+			}
+			return encodeSeconds(et, b, int64(cur)+secs)
+		}
+	}
+
+	secs, err := durationSeconds(v, time.Hour)
+	if err != nil {
+		return err
+	}
+	return encodeSeconds(et, b, secs)
 }
 
-func (codec sec2DurationCodec) MarshalJSON() ([]byte, error) {
+func (HourDiffSec2HourCodec) Relative() bool { return true }
+
+func (codec HourDiffSec2HourCodec) MarshalJSON() ([]byte, error) {
 	t := strings.Split(reflect.TypeOf(codec).String(), ".")
 	return []byte(fmt.Sprintf("\"%s\"", t[len(t)-1])), nil
 }
 
 /*
-// Codec mappingTime53
+// Codec MappingTime53
 Vier Schaltfenster mit je einem Ein- u. Ausschaltpunkt.
 Speicherung der Zeiten im 5+3 Format (Stunde + 10-Minuten Raster)
 
@@ -516,13 +827,14 @@ func fmtDuration53(d time.Duration) string {
 	return fmt.Sprintf("%02dh%02d", h, m)
 }
 
-type mappingTime53 struct{}
+// MappingTime53 handles switching times stored in the 5+3 format
+type MappingTime53 struct{}
 
 func time532Duration(b byte) time.Duration {
 	return (time.Duration(b>>3) * time.Hour) + (time.Duration(b&7) * time.Minute * 10)
 }
 
-func (mappingTime53) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
+func (MappingTime53) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
 	var t onoff53
 	var d []onoff53
 	var w [][]onoff53
@@ -531,7 +843,7 @@ func (mappingTime53) Decode(et *EventType, b *[]byte) (v interface{}, err error)
 	chunkNum := uint8(len(*b) / int(chunkSize))
 
 	if (len(*b) % int(chunkSize)) != 0 {
-		return v, fmt.Errorf("Codec mappingTime53 can not decode: data length is not a multiple of chunk size (%d)", chunkSize)
+		return v, fmt.Errorf("Codec MappingTime53 can not decode: data length is not a multiple of chunk size (%d)", chunkSize)
 	}
 
 	for i := uint8(0); i < chunkNum; i++ {
@@ -548,14 +860,15 @@ func (mappingTime53) Decode(et *EventType, b *[]byte) (v interface{}, err error)
 
 	return w, nil
 }
-func (mappingTime53) Encode(et *EventType, b *[]byte, v interface{}) (err error) { return nil }
-func (codec mappingTime53) MarshalJSON() ([]byte, error) {
+func (MappingTime53) Encode(et *EventType, b *[]byte, v interface{}) (err error) { return nil }
+func (MappingTime53) WholeBlock() bool                                           { return true }
+func (codec MappingTime53) MarshalJSON() ([]byte, error) {
 	t := strings.Split(reflect.TypeOf(codec).String(), ".")
 	return []byte(fmt.Sprintf("\"%s\"", t[len(t)-1])), nil
 }
 
 /*
-// Code mappingRaster152
+// Code MappingRaster152
 Timer 24h, für jede 1/4 Stunde 2 Bit.
 Werteliste:  0: Stand by
 
@@ -572,58 +885,205 @@ Beispiel:
 	Bit 4,5 = 30min..<45min
 	Bit 6,7 = 45min..<60min
 */
-type mappingRaster152 struct{}
+// MappingRaster152 handles the 15 minute raster of 2 bit values
+type MappingRaster152 struct{}
 
-func (mappingRaster152) Decode(et *EventType, b *[]byte) (v interface{}, err error) { return (*b), nil }
-func (mappingRaster152) Encode(et *EventType, b *[]byte, v interface{}) (err error) { return nil }
-func (codec mappingRaster152) MarshalJSON() ([]byte, error) {
+func (MappingRaster152) Decode(et *EventType, b *[]byte) (v interface{}, err error) { return (*b), nil }
+func (MappingRaster152) Encode(et *EventType, b *[]byte, v interface{}) (err error) { return nil }
+func (MappingRaster152) WholeBlock() bool                                           { return true }
+func (codec MappingRaster152) MarshalJSON() ([]byte, error) {
 	t := strings.Split(reflect.TypeOf(codec).String(), ".")
 	return []byte(fmt.Sprintf("\"%s\"", t[len(t)-1])), nil
 }
 
 /*
-// Codec mappingErrors
+// Codec MappingErrors
 
-	TODO: Format spec check
 	Fehlerhistorie
 	ByteLenght 90 / BlockFactor 10 =  9 Bytes / Eintrag
-	Byte 0 Fehler?, Bytes1..8 DateTimeBCD
+	Byte 0 Fehlercode, Bytes1..8 DateTimeBCD
+	Empty slots have a Fehlercode of 0
 */
-type mappingErrors struct{}
+// MappingErrors handles the error history. Decoded entries are described via Catalog if it is set.
+type MappingErrors struct {
+	Catalog FaultCatalog
+}
 
+// ErrEntry is a single entry of the error history
 type ErrEntry struct {
-	errType byte
-	errDate time.Time
+	Code      byte
+	Date      time.Time
+	EventType string     // ID of the EventType the entry was read from
+	Fault     *FaultCode // nil if the code is not in the fault-code catalogue
+	Error     string     // Set instead of the other fields but EventType if it could not be read
 }
 
 func (e ErrEntry) String() string {
-	return fmt.Sprintf("0x%0x: %v", e.errType, e.errDate)
+	if e.Error != "" {
+		return fmt.Sprintf("%v: %v", e.EventType, e.Error)
+	}
+	if e.Fault != nil {
+		return fmt.Sprintf("0x%02X: %v (%s)", e.Code, e.Date, e.Fault.Description)
+	}
+	return fmt.Sprintf("0x%02X: %v", e.Code, e.Date)
 }
 
 func (e ErrEntry) MarshalJSON() ([]byte, error) {
-	return []byte(fmt.Sprintf("{ \"errtype\": \"%d\", \"errdate\": \"%s\" }", e.errType, e.errDate)), nil
+	if e.Error != "" {
+		return json.Marshal(struct {
+			EventType string `json:"event_type"`
+			Error     string `json:"error"`
+		}{e.EventType, e.Error})
+	}
+	j := struct {
+		Code        string    `json:"code"`
+		Date        time.Time `json:"date"`
+		EventType   string    `json:"event_type,omitempty"`
+		Description string    `json:"description,omitempty"`
+		Severity    string    `json:"severity,omitempty"`
+		Action      string    `json:"action,omitempty"`
+	}{Code: fmt.Sprintf("0x%02X", e.Code), Date: e.Date, EventType: e.EventType}
+	if e.Fault != nil {
+		j.Description = e.Fault.Description
+		j.Severity = e.Fault.Severity
+		j.Action = e.Fault.Action
+	}
+	return json.Marshal(j)
 }
 
-func (mappingErrors) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
+func (codec MappingErrors) Decode(et *EventType, b *[]byte) (v interface{}, err error) {
 	e := []ErrEntry{}
-	var errNum byte
-	var errDate time.Time
 
-	for j := 0; j < len((*b)); j += 9 {
-		errNum = (*b)[j]
-		c := append([]byte{}, (*b)[j+1:j+8]...)
-		errDate, _ = decodeBCDDate(c)
-		e = append(e, ErrEntry{errNum, errDate})
+	for j := 0; j+9 <= len((*b)); j += 9 {
+		if (*b)[j] == 0 {
+			// Empty slot
+			continue
+		}
+		entry := ErrEntry{Code: (*b)[j], EventType: et.ID}
+		entry.Date, _ = decodeBCDDate(append([]byte{}, (*b)[j+1:j+9]...))
+		if f, ok := codec.Catalog[entry.Code]; ok {
+			entry.Fault = &f
+		}
+		e = append(e, entry)
 	}
 	return e, nil
 }
-func (mappingErrors) Encode(et *EventType, b *[]byte, v interface{}) (err error) { return nil }
+func (MappingErrors) Encode(et *EventType, b *[]byte, v interface{}) (err error) { return nil }
 
-func (codec mappingErrors) MarshalJSON() ([]byte, error) {
+func (MappingErrors) WholeBlock() bool { return true }
+
+func (codec MappingErrors) MarshalJSON() ([]byte, error) {
 	t := strings.Split(reflect.TypeOf(codec).String(), ".")
 	return []byte(fmt.Sprintf("\"%s\"", t[len(t)-1])), nil
 }
 
+// getBits returns n bits of b starting at bit pos. Bits are counted from the least significant bit of the first byte,
+// a bit field may span byte boundaries with the following bytes holding the more significant bits.
+func getBits(b []byte, pos, n uint) (d uint64, err error) {
+	if n > 64 {
+		return 0, fmt.Errorf("can not handle bit fields longer than 64 bits")
+	}
+	if (pos+n+7)/8 > uint(len(b)) {
+		return 0, fmt.Errorf("bit field (BitPosition:%v, BitLength:%v) exceeds data length %v", pos, n, len(b))
+	}
+	for i := uint(0); i < n; i++ {
+		p := pos + i
+		d |= uint64((b[p/8]>>(p%8))&1) << i
+	}
+	return d, nil
+}
+
+// setBits sets n bits of b starting at bit pos to the n least significant bits of d, see getBits
+func setBits(b []byte, pos, n uint, d uint64) error {
+	if n > 64 {
+		return fmt.Errorf("can not handle bit fields longer than 64 bits")
+	}
+	if (pos+n+7)/8 > uint(len(b)) {
+		return fmt.Errorf("bit field (BitPosition:%v, BitLength:%v) exceeds data length %v", pos, n, len(b))
+	}
+	for i := uint(0); i < n; i++ {
+		p := pos + i
+		if (d>>i)&1 == 1 {
+			b[p/8] |= 1 << (p % 8)
+		} else {
+			b[p/8] &^= 1 << (p % 8)
+		}
+	}
+	return nil
+}
+
+// signExtend interprets the n least significant bits of d as a two's complement number
+func signExtend(d uint64, n uint) int64 {
+	shift := 64 - n
+	return int64(d<<shift) >> shift
+}
+
+// float64Value converts a value of a basic numeric type to float64, which keeps integers like counters of seconds exact
+func float64Value(v interface{}) (f float64, err error) {
+	switch v := v.(type) {
+	case float32:
+		f = float64(v)
+	case float64:
+		f = v
+	case int:
+		f = float64(v)
+	case int8:
+		f = float64(v)
+	case int16:
+		f = float64(v)
+	case int32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case uint:
+		f = float64(v)
+	case uint8:
+		f = float64(v)
+	case uint16:
+		f = float64(v)
+	case uint32:
+		f = float64(v)
+	case uint64:
+		f = float64(v)
+	default:
+		return f, fmt.Errorf("value must be a basic numeric type")
+	}
+	return f, nil
+}
+
+// float32Value converts a value of a basic numeric type to float32
+func float32Value(v interface{}) (f float32, err error) {
+	switch v := v.(type) {
+	case float32:
+		f = v
+	case float64:
+		f = float32(v)
+	case int:
+		f = float32(v)
+	case int8:
+		f = float32(v)
+	case int16:
+		f = float32(v)
+	case int32:
+		f = float32(v)
+	case int64:
+		f = float32(v)
+	case uint:
+		f = float32(v)
+	case uint8:
+		f = float32(v)
+	case uint16:
+		f = float32(v)
+	case uint32:
+		f = float32(v)
+	case uint64:
+		f = float32(v)
+	default:
+		return f, fmt.Errorf("value must be a basic numeric type")
+	}
+	return f, nil
+}
+
 func fromBCD(b byte) int {
 	return ((int(b)>>4)*10 + (int(b) & 0x0f))
 }
@@ -0,0 +1,318 @@
+package vogo
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSecondsCodecs(t *testing.T) {
+	tests := []struct {
+		name    string
+		codec   Codec
+		length  uint8
+		old     []byte
+		value   interface{}
+		want    []byte // nil if encoding must fail
+		decoded uint64 // seconds decoded from want
+	}{
+		{"Sec2Hour number", Sec2HourCodec{}, 4, []byte{0, 0, 0, 0}, 1000, []byte{0x80, 0xEE, 0x36, 0x00}, 3600000},
+		{"Sec2Hour fraction", Sec2HourCodec{}, 4, []byte{0, 0, 0, 0}, 1.5, []byte{0x18, 0x15, 0x00, 0x00}, 5400},
+		{"Sec2Hour duration string", Sec2HourCodec{}, 4, []byte{0, 0, 0, 0}, "90m", []byte{0x18, 0x15, 0x00, 0x00}, 5400},
+		{"Sec2Hour time.Duration", Sec2HourCodec{}, 4, []byte{0, 0, 0, 0}, 2 * time.Minute, []byte{0x78, 0x00, 0x00, 0x00}, 120},
+		{"Sec2Hour object", Sec2HourCodec{}, 4, []byte{0, 0, 0, 0}, map[string]interface{}{"minutes": 2.0}, []byte{0x78, 0x00, 0x00, 0x00}, 120},
+		{"Sec2Hour DurationValue", Sec2HourCodec{}, 4, []byte{0, 0, 0, 0}, DurationValue{Seconds: 1, Unit: time.Hour}, []byte{0x01, 0x00, 0x00, 0x00}, 1},
+		{"Sec2Hour beyond float32 precision", Sec2HourCodec{}, 4, []byte{0, 0, 0, 0}, map[string]interface{}{"seconds": 16777217}, []byte{0x01, 0x00, 0x00, 0x01}, 16777217},
+		{"Sec2Minute beyond float32 precision", Sec2MinuteCodec{}, 4, []byte{0, 0, 0, 0}, 16777217, []byte{0x3C, 0x00, 0x00, 0x3C}, 1006633020},
+		{"Sec2Hour largest value", Sec2HourCodec{}, 2, []byte{0, 0}, map[string]interface{}{"seconds": 65535}, []byte{0xFF, 0xFF}, 65535},
+		{"Sec2Hour overflow", Sec2HourCodec{}, 2, []byte{0, 0}, map[string]interface{}{"seconds": 65536}, nil, 0},
+		{"Sec2Hour overflow in hours", Sec2HourCodec{}, 2, []byte{0, 0}, 20, nil, 0},
+		{"Sec2Hour negative", Sec2HourCodec{}, 4, []byte{0, 0, 0, 0}, -1, nil, 0},
+		{"Sec2Hour bad string", Sec2HourCodec{}, 4, []byte{0, 0, 0, 0}, "soon", nil, 0},
+		{"Sec2Hour object without unit", Sec2HourCodec{}, 4, []byte{0, 0, 0, 0}, map[string]interface{}{"days": 1}, nil, 0},
+		{"Sec2Minute number", Sec2MinuteCodec{}, 4, []byte{0, 0, 0, 0}, 90, []byte{0x18, 0x15, 0x00, 0x00}, 5400},
+		// 10 hours before writing
+		{"HourDiff number is absolute", HourDiffSec2HourCodec{}, 4, []byte{0xA0, 0x8C, 0x00, 0x00}, 1.5, []byte{0x18, 0x15, 0x00, 0x00}, 5400},
+		{"HourDiff add", HourDiffSec2HourCodec{}, 4, []byte{0xA0, 0x8C, 0x00, 0x00}, map[string]interface{}{"add": 1}, []byte{0xB0, 0x9A, 0x00, 0x00}, 39600},
+		{"HourDiff add negative", HourDiffSec2HourCodec{}, 4, []byte{0xA0, 0x8C, 0x00, 0x00}, map[string]interface{}{"add": -1.5}, []byte{0x88, 0x77, 0x00, 0x00}, 30600},
+		{"HourDiff add duration string", HourDiffSec2HourCodec{}, 4, []byte{0xA0, 0x8C, 0x00, 0x00}, map[string]interface{}{"add": "-30m"}, []byte{0x98, 0x85, 0x00, 0x00}, 34200},
+		{"HourDiff add below zero", HourDiffSec2HourCodec{}, 4, []byte{0xA0, 0x8C, 0x00, 0x00}, map[string]interface{}{"add": -11}, nil, 0},
+		{"HourDiff add with other keys", HourDiffSec2HourCodec{}, 4, []byte{0xA0, 0x8C, 0x00, 0x00}, map[string]interface{}{"add": 1, "hours": 2}, nil, 0},
+		{"HourDiff reset", HourDiffSec2HourCodec{}, 4, []byte{0xA0, 0x8C, 0x00, 0x00}, map[string]interface{}{"seconds": 0}, []byte{0, 0, 0, 0}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			et := &EventType{ID: tt.name, BlockLength: tt.length, ByteLength: tt.length, Codec: tt.codec}
+			b := append([]byte{}, tt.old...)
+			err := tt.codec.Encode(et, &b, tt.value)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("encoding %v gave % X, want an error", tt.value, b)
+				}
+				if !bytes.Equal(b, tt.old) {
+					t.Errorf("failed encoding changed the data to % X", b)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("encoding %v: %v", tt.value, err)
+			}
+			if !bytes.Equal(b, tt.want) {
+				t.Fatalf("encoding %v gave % X, want % X", tt.value, b, tt.want)
+			}
+
+			v, err := tt.codec.Decode(et, &b)
+			if err != nil {
+				t.Fatalf("decoding % X: %v", b, err)
+			}
+			if d, ok := v.(DurationValue); !ok || d.Seconds != tt.decoded {
+				t.Errorf("decoding % X gave %#v, want %v seconds", b, v, tt.decoded)
+			}
+		})
+	}
+}
+
+func TestSecondsByteLength(t *testing.T) {
+	et := &EventType{ByteLength: 9, BlockLength: 9}
+	b := make([]byte, 9)
+	if _, err := decodeSeconds(et, &b); err == nil {
+		t.Errorf("decoding 9 bytes did not fail")
+	}
+
+	et = &EventType{ByteLength: 4, BytePosition: 1, BlockLength: 4}
+	b = make([]byte, 4)
+	if err := encodeSeconds(et, &b, 1); err == nil {
+		t.Errorf("encoding beyond the data did not fail")
+	}
+}
+
+func TestBitFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		parameter string
+		pos, n    uint8
+		old       []byte
+		value     float32
+		want      []byte // nil if encoding must fail
+	}{
+		{"inner bits", "Byte", 4, 3, []byte{0xFF}, 2, []byte{0xAF}},
+		{"inner bits cleared", "Byte", 4, 3, []byte{0xFF}, 0, []byte{0x8F}},
+		{"single bit", "Byte", 0, 1, []byte{0x00}, 1, []byte{0x01}},
+		{"across bytes", "Byte", 6, 4, []byte{0x00, 0x00}, 15, []byte{0xC0, 0x03}},
+		{"across bytes keeps others", "Byte", 6, 4, []byte{0xFF, 0xFF}, 0, []byte{0x3F, 0xFC}},
+		{"in second byte", "Byte", 9, 2, []byte{0x00, 0x00}, 3, []byte{0x00, 0x06}},
+		{"signed negative", "SByte", 2, 4, []byte{0x00}, -3, []byte{0x34}},
+		{"signed lowest", "SByte", 1, 4, []byte{0x00}, -8, []byte{0x10}},
+		{"signed upper nibble", "SByte", 0, 4, []byte{0x00}, -8, []byte{0x80}},
+		{"unsigned overflow", "Byte", 4, 3, []byte{0xFF}, 8, nil},
+		{"unsigned negative", "Byte", 4, 3, []byte{0xFF}, -1, nil},
+		{"signed overflow", "SByte", 0, 4, []byte{0x00}, 8, nil},
+		{"signed underflow", "SByte", 0, 4, []byte{0x00}, -9, nil},
+		{"beyond data", "Byte", 6, 4, []byte{0x00}, 1, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			et := &EventType{ID: tt.name, Parameter: tt.parameter, BlockLength: uint8(len(tt.old)), ByteLength: uint8(len(tt.old)),
+				BitPosition: tt.pos, BitLength: tt.n, ConversionFactor: 1, Codec: DivMulOffsetCodec{}}
+			b := append([]byte{}, tt.old...)
+			err := et.Codec.Encode(et, &b, tt.value)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("encoding %v gave % X, want an error", tt.value, b)
+				}
+				if !bytes.Equal(b, tt.old) {
+					t.Errorf("failed encoding changed the data to % X", b)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("encoding %v: %v", tt.value, err)
+			}
+			if !bytes.Equal(b, tt.want) {
+				t.Fatalf("encoding %v gave % X, want % X", tt.value, b, tt.want)
+			}
+
+			v, err := et.Codec.Decode(et, &b)
+			if err != nil || v != tt.value {
+				t.Errorf("decoding % X gave %v, %v, want %v", b, v, err, tt.value)
+			}
+		})
+	}
+}
+
+func TestNibbles(t *testing.T) {
+	// BitPosition 0 is the upper and 4 the lower nibble of the byte at BytePosition
+	tests := []struct {
+		bytePos, bitPos uint8
+		want            float32
+	}{
+		{0, 0, 0xA},
+		{0, 4, 0x5},
+		{1, 0, 0x3},
+		{1, 4, 0xC},
+	}
+
+	for _, tt := range tests {
+		et := &EventType{ID: "Nibble", Parameter: "Byte", BlockLength: 2, ByteLength: 1, BytePosition: tt.bytePos,
+			BitPosition: tt.bitPos, BitLength: 4, ConversionFactor: 1, Codec: DivMulOffsetCodec{}}
+		b := []byte{0xA5, 0x3C}
+		v, err := et.Codec.Decode(et, &b)
+		if err != nil || v != tt.want {
+			t.Errorf("BytePosition %v, BitPosition %v: decoding % X gave %v, %v, want %v", tt.bytePos, tt.bitPos, b, v, err, tt.want)
+		}
+
+		err = et.Codec.Encode(et, &b, 0)
+		want := []byte{0xA5, 0x3C}
+		want[tt.bytePos] &^= 0xF0 >> tt.bitPos
+		if err != nil || !bytes.Equal(b, want) {
+			t.Errorf("BytePosition %v, BitPosition %v: encoding 0 gave % X, %v, want % X", tt.bytePos, tt.bitPos, b, err, want)
+		}
+	}
+}
+
+func TestDivMulOffsetBytes(t *testing.T) {
+	tests := []struct {
+		parameter string
+		length    uint8
+		factor    float32
+		value     float32
+		want      []byte
+	}{
+		{"Byte", 1, 1, 200, []byte{0xC8}},
+		{"SByte", 1, 1, -2, []byte{0xFE}},
+		{"Int", 2, 0.1, 21.5, []byte{0xD7, 0x00}},
+		{"SInt", 2, 0.1, -1.5, []byte{0xF1, 0xFF}},
+		{"IntHighByteFirst", 2, 1, 0x1234, []byte{0x12, 0x34}},
+		{"SIntHighByteFirst", 2, 1, -2, []byte{0xFF, 0xFE}},
+		{"Int", 3, 1, 0x123456, []byte{0x56, 0x34, 0x12}},
+		{"SInt4", 4, 1, -2, []byte{0xFE, 0xFF, 0xFF, 0xFF}},
+	}
+
+	for _, tt := range tests {
+		et := &EventType{ID: tt.parameter, Parameter: tt.parameter, BlockLength: tt.length, ByteLength: tt.length, ConversionFactor: tt.factor, Codec: DivMulOffsetCodec{}}
+		b := make([]byte, tt.length)
+		err := et.Codec.Encode(et, &b, tt.value)
+		if err != nil || !bytes.Equal(b, tt.want) {
+			t.Errorf("%v: encoding %v gave % X, %v, want % X", tt.parameter, tt.value, b, err, tt.want)
+			continue
+		}
+		v, err := et.Codec.Decode(et, &b)
+		if err != nil || v != tt.value {
+			t.Errorf("%v: decoding % X gave %v, %v, want %v", tt.parameter, b, v, err, tt.value)
+		}
+	}
+}
+
+func TestBlockElements(t *testing.T) {
+	// Two 2-byte values, like a pair of temperatures
+	et := &EventType{ID: "Pair", Parameter: "Int", BlockLength: 4, BlockFactor: 2, ByteLength: 2, ConversionFactor: 0.1, Codec: DivMulOffsetCodec{}}
+	if n := et.Elements(); n != 2 {
+		t.Fatalf("Elements() = %v, want 2", n)
+	}
+
+	b := []byte{0xD7, 0x00, 0x2C, 0x01}
+	v, err := et.decodeBlock(&b)
+	values, ok := v.([]interface{})
+	if err != nil || !ok || len(values) != 2 || values[0] != float32(21.5) || values[1] != float32(30) {
+		t.Fatalf("decodeBlock(% X) = %v, %v", b, v, err)
+	}
+
+	err = et.encodeIndex(&b, 1, 22)
+	if err != nil || !bytes.Equal(b, []byte{0xD7, 0x00, 0xDC, 0x00}) {
+		t.Errorf("encodeIndex(1, 22) gave % X, %v", b, err)
+	}
+	v, err = et.decodeIndex(&b, 1)
+	if err != nil || v != float32(22) {
+		t.Errorf("decodeIndex(1) = %v, %v", v, err)
+	}
+
+	err = et.encodeBlock(&b, []interface{}{10, 20})
+	if err != nil || !bytes.Equal(b, []byte{0x64, 0x00, 0xC8, 0x00}) {
+		t.Errorf("encodeBlock([10 20]) gave % X, %v", b, err)
+	}
+	if err = et.encodeBlock(&b, []interface{}{10}); err == nil {
+		t.Errorf("encodeBlock with too few values did not fail")
+	}
+	if err = et.encodeBlock(&b, 10); err == nil {
+		t.Errorf("encodeBlock with a single value did not fail")
+	}
+	short := b[:3]
+	if _, err = et.decodeIndex(&short, 1); err == nil {
+		t.Errorf("decodeIndex beyond the data did not fail")
+	}
+
+	// A BytePosition counting from the start of the block is taken relative to the element
+	et = &EventType{ID: "Second", Parameter: "Byte", BlockLength: 4, BlockFactor: 2, ByteLength: 1, BytePosition: 3, ConversionFactor: 1, Codec: DivMulOffsetCodec{}}
+	b = []byte{1, 2, 3, 4}
+	v, err = et.decodeBlock(&b)
+	values, ok = v.([]interface{})
+	if err != nil || !ok || values[0] != float32(2) || values[1] != float32(4) {
+		t.Errorf("decodeBlock(% X) with BytePosition 3 = %v, %v", b, v, err)
+	}
+
+	for _, tt := range []struct {
+		blockLength, blockFactor uint8
+		codec                    Codec
+	}{
+		{4, 0, DivMulOffsetCodec{}},
+		{4, 1, DivMulOffsetCodec{}},
+		{5, 2, DivMulOffsetCodec{}},
+		{4, 2, NopCodec{}},
+	} {
+		et := &EventType{BlockLength: tt.blockLength, BlockFactor: tt.blockFactor, Codec: tt.codec}
+		if n := et.Elements(); n != 1 {
+			t.Errorf("Elements() of BlockLength %v, BlockFactor %v, %T = %v, want 1", tt.blockLength, tt.blockFactor, tt.codec, n)
+		}
+	}
+}
+
+func TestDecodeDefault(t *testing.T) {
+	tests := []struct {
+		name  string
+		et    EventType
+		want  interface{}
+		fails bool
+	}{
+		{"number", EventType{Parameter: "Byte", BlockLength: 1, ByteLength: 1, ConversionFactor: 1, Codec: DivMulOffsetCodec{}, ALZ: "20"}, float32(20), false},
+		{"decimal comma", EventType{Parameter: "Int", BlockLength: 2, ByteLength: 2, ConversionFactor: 0.1, Codec: DivMulOffsetCodec{}, ALZ: "21,5"}, float32(21.5), false},
+		{"none", EventType{Parameter: "Byte", BlockLength: 1, ByteLength: 1, ConversionFactor: 1, Codec: DivMulOffsetCodec{}}, nil, false},
+		{"number for a date", EventType{BlockLength: 4, ByteLength: 4, Codec: DateBCDCodec{}, ALZ: "0"}, nil, true},
+		{"number for date and time", EventType{BlockLength: 8, ByteLength: 8, Codec: DateTimeBCDCodec{}, ALZ: "0"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.et.decodeDefault()
+			if tt.fails {
+				if err == nil {
+					t.Errorf("got %v, want an error", v)
+				}
+				return
+			}
+			if err != nil || v != tt.want {
+				t.Errorf("got %v, %v, want %v", v, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelativeCodec(t *testing.T) {
+	// A codec wrapping HourDiffSec2HourCodec, e.g. registered for a vendor specific conversion
+	type wrapped struct{ HourDiffSec2HourCodec }
+
+	for _, tt := range []struct {
+		codec Codec
+		want  bool
+	}{
+		{HourDiffSec2HourCodec{}, true},
+		{wrapped{}, true},
+		{Sec2HourCodec{}, false},
+		{DivMulOffsetCodec{}, false},
+	} {
+		if got := isRelativeCodec(tt.codec); got != tt.want {
+			t.Errorf("isRelativeCodec(%T) = %v, want %v", tt.codec, got, tt.want)
+		}
+	}
+}
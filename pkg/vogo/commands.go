@@ -43,11 +43,49 @@ func (o *Device) RawCmd(cmd FsmCmd) FsmResult {
 // RawCmds takes a raw FsmCmd... and returns []FsmResult
 // It makes use of caching. Set Device.CacheDuration to 0 to disable
 // ATTN: Operates in chunks of chunkSize if cmd.ResultLen exceeds chunkSize
+// If a WritePolicy is set, all commands but reads are subject to its Raw ranges like RawWrite. If any of them is
+// denied, none is sent and every result carries an error.
 func (o *Device) RawCmds(cmds ...FsmCmd) (ress []FsmResult) {
-	const chunkSize = 32 // Max is 37?
+	err := o.checkRawCmds(cmds)
+	if err != nil {
+		for _, cmd := range cmds {
+			ress = append(ress, FsmResult{ID: cmd.ID, Err: err})
+		}
+		return ress
+	}
 
 	o.cmdLock.Lock()
 	defer o.cmdLock.Unlock()
+	return o.rawCmds(true, cmds...)
+}
+
+// checkRawCmds returns a WriteDeniedError if the WritePolicy forbids any of the commands which are not reads.
+// Without a WritePolicy all commands are allowed.
+func (o *Device) checkRawCmds(cmds []FsmCmd) error {
+	p := o.WritePolicy()
+	if p == nil {
+		return nil
+	}
+	for _, cmd := range cmds {
+		if isReadCmd(cmd.Command) {
+			continue
+		}
+		n := len(cmd.Args)
+		if n == 0 {
+			n = 1
+		}
+		err := p.checkRaw(bytes2Addr(cmd.Address), n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rawCmds does the work of RawCmds, the caller must hold cmdLock. Reads bypass the cache if useCache is false.
+func (o *Device) rawCmds(useCache bool, cmds ...FsmCmd) (ress []FsmResult) {
+	const chunkSize = 32 // Max is 37?
+
 	defer func() {
 		// recover from panic caused by writing to a closed channel
 		if r := recover(); r != nil {
@@ -61,7 +99,10 @@ func (o *Device) RawCmds(cmds ...FsmCmd) (ress []FsmResult) {
 		addr := bytes2Addr(cmd.Address)
 		now := time.Now()
 
-		if isReadCmd(cmd.Command) && o.CacheDuration > 0 && cmd.ResultLen > 0 {
+		// Only the virtual address space of the device itself is covered by the cache
+		cacheable := len(cmd.Prefix) == 0 && virtualCmds[cmd.Command]
+
+		if useCache && cacheable && isReadCmd(cmd.Command) && o.CacheDuration > 0 && cmd.ResultLen > 0 {
 			c, oldestCacheTime := o.getCache(addr, uint16(cmd.ResultLen))
 			if c != nil && now.Sub(oldestCacheTime) < o.CacheDuration {
 				log.Debugf("Cache hit for FsmCmd at addr: %#x, Body: %# x", addr, c)
@@ -93,7 +134,7 @@ func (o *Device) RawCmds(cmds ...FsmCmd) (ress []FsmResult) {
 			if !ok {
 				return []FsmResult{FsmResult{Err: io.EOF}}
 			}
-			if result.Err == nil {
+			if result.Err == nil && cacheable {
 				var t time.Time
 				if isReadCmd(cmd.Command) {
 					t = now
@@ -102,7 +143,7 @@ func (o *Device) RawCmds(cmds ...FsmCmd) (ress []FsmResult) {
 				for i := uint16(0); i < uint16(len(result.Body)); i++ {
 					(*o.Mem)[uint16(addr)+i] = &MemType{result.Body[i], t}
 				}
-			} else {
+			} else if result.Err != nil {
 				// Save an error for multi-block cmds
 				err = result.Err
 			}
@@ -135,7 +176,7 @@ func NewUUID() (uuid [16]byte) {
 
 // VRead is the generic command to read Events of arbitrary data types
 func (o *Device) VRead(ID string) (data interface{}, err error) {
-	et, ok := o.DataPoint.EventTypes[ID]
+	et, ok := o.DataPoint().EventType(ID)
 	if !ok {
 		return data, fmt.Errorf("EventType %v not found", ID)
 	}
@@ -144,78 +185,315 @@ func (o *Device) VRead(ID string) (data interface{}, err error) {
 		return data, fmt.Errorf("EventType %v is not readable at address %v", et.ID, et.Address)
 	}
 
-	step := et.BlockLength
-	if et.BlockFactor > 0 {
-		step = et.BlockLength / et.BlockFactor
+	o.cmdLock.Lock()
+	b, err := o.readBlock(et, true)
+	o.cmdLock.Unlock()
+	if err != nil {
+		return data, err
 	}
 
-	cmd := FsmCmd{ID: NewUUID(), Command: et.FCRead, Address: addr2Bytes(et.Address), ResultLen: byte(step)}
-	var res FsmResult
-	b := []byte{}
-	for i := uint8(0); i < et.BlockLength; i += step {
+	data, err = et.decodeBlock(&b)
+	return data, err
+}
 
-		cmd.Address = addr2Bytes(et.Address + AddressT(i))
+// EventResult is the outcome of reading an EventType with VReadMulti
+type EventResult struct {
+	Value interface{} `json:"value,omitempty"`
+	Unit  string      `json:"unit,omitempty"`
+	Error string      `json:"error,omitempty"`
+	Time  *time.Time  `json:"time,omitempty"` // When the value was read from the device, earlier if it came from the cache
+}
 
-		res = o.RawCmd(cmd)
+// VReadMulti reads several EventTypes in a single pass without other commands in between, so the values are as
+// consistent as possible. Errors are reported per EventType, keyed by the ID or alias asked for.
+func (o *Device) VReadMulti(IDs ...string) map[string]EventResult {
+	results := make(map[string]EventResult, len(IDs))
 
-		b = append(b, res.Body...)
-		if res.Err != nil {
-			return data, res.Err
+	type span struct {
+		et         *EventType
+		start, end int // Range of the commands reading the EventType
+	}
+	spans := make(map[string]span)
+	var cmds []FsmCmd
+
+	dp := o.DataPoint()
+	for _, ID := range IDs {
+		if _, ok := spans[ID]; ok {
+			continue
+		}
+		et, ok := dp.EventType(ID)
+		if !ok {
+			results[ID] = EventResult{Error: fmt.Sprintf("EventType %v not found", ID)}
+			continue
 		}
+		if et.FCRead == 0 {
+			results[ID] = EventResult{Error: fmt.Sprintf("EventType %v is not readable at address %v", et.ID, et.Address)}
+			continue
+		}
+
+		step := et.step()
+		sp := span{et: et, start: len(cmds)}
+		for i := uint8(0); i < et.BlockLength; i += step {
+			cmds = append(cmds, FsmCmd{ID: NewUUID(), Command: et.FCRead, Address: addr2Bytes(et.Address + AddressT(i)), ResultLen: byte(step), Prefix: et.PrefixRead})
+		}
+		sp.end = len(cmds)
+		spans[ID] = sp
 	}
 
-	data, err = et.Codec.Decode(et, &b)
-	return data, err
+	o.cmdLock.Lock()
+	ress := o.rawCmds(true, cmds...)
+	now := time.Now()
+	times := make([]time.Time, len(cmds))
+	for n, cmd := range cmds {
+		times[n] = now
+		if len(cmd.Prefix) == 0 && virtualCmds[cmd.Command] {
+			if c, t := o.getCache(bytes2Addr(cmd.Address), uint16(cmd.ResultLen)); c != nil {
+				times[n] = t
+			}
+		}
+	}
+	o.cmdLock.Unlock()
+
+	for ID, sp := range spans {
+		res := EventResult{}
+		t := now
+		b := []byte{}
+		for n := sp.start; n < sp.end; n++ {
+			if n >= len(ress) {
+				// The pass was aborted, e.g. because the link is down
+				res.Error = io.EOF.Error()
+				break
+			}
+			if ress[n].Err != nil {
+				res.Error = ress[n].Err.Error()
+				break
+			}
+			b = append(b, ress[n].Body...)
+			if times[n].Before(t) {
+				t = times[n]
+			}
+		}
+		if res.Error == "" {
+			v, err := sp.et.decodeBlock(&b)
+			if err != nil {
+				res.Error = err.Error()
+			} else {
+				res.Value = v
+				res.Unit = sp.et.Unit
+				res.Time = &t
+			}
+		}
+		results[ID] = res
+	}
+	return results
 }
 
-// VWrite is the generic command to write Events of arbitrary data types
-func (o *Device) VWrite(ID string, data interface{}) (err error) {
-	et, ok := o.DataPoint.EventTypes[ID]
+// VWrite is the generic command to write Events of arbitrary data types.
+// Block-factored EventTypes expect a []interface{} holding a value for every element.
+// The optional WriteOrigin is passed on to OnWrite, this applies to all write methods.
+func (o *Device) VWrite(ID string, data interface{}, origins ...WriteOrigin) (err error) {
+	et, ok := o.DataPoint().EventType(ID)
 	if !ok {
 		return fmt.Errorf("EventType %v not found", ID)
 	}
 
-	if et.FCWrite == 0 {
-		return fmt.Errorf("EventType %v is not writable at address %v", et.ID, et.Address)
+	_, err = o.vWrite(et, -1, data, origin(origins))
+	return err
+}
+
+// VWriteIndex writes a single element of a block-factored EventType
+func (o *Device) VWriteIndex(ID string, index int, data interface{}, origins ...WriteOrigin) (err error) {
+	et, ok := o.DataPoint().EventType(ID)
+	if !ok {
+		return fmt.Errorf("EventType %v not found", ID)
+	}
+
+	_, err = o.vWrite(et, index, data, origin(origins))
+	return err
+}
+
+// VWriteClamped works like VWrite, but instead of rejecting numeric values outside of the borders of the EventType
+// or not matching its stepping, it clamps them to the borders and rounds them to the nearest step.
+// It returns the value which was actually written.
+func (o *Device) VWriteClamped(ID string, data interface{}, origins ...WriteOrigin) (written interface{}, err error) {
+	return o.VWriteIndexClamped(ID, -1, data, origins...)
+}
+
+// VWriteIndexClamped works like VWriteClamped for a single element of a block-factored EventType.
+// An index of -1 writes the whole EventType.
+func (o *Device) VWriteIndexClamped(ID string, index int, data interface{}, origins ...WriteOrigin) (written interface{}, err error) {
+	et, ok := o.DataPoint().EventType(ID)
+	if !ok {
+		return nil, fmt.Errorf("EventType %v not found", ID)
+	}
+
+	data = et.clampData(index, data)
+
+	b, err := o.vWrite(et, index, data, origin(origins))
+	if err != nil {
+		return nil, err
+	}
+	if index >= 0 {
+		return et.decodeIndex(&b, index)
+	}
+	return et.decodeBlock(&b)
+}
+
+// clampData clamps the value for an element of the EventType, or all values if index is -1
+func (et *EventType) clampData(index int, data interface{}) interface{} {
+	if values, ok := data.([]interface{}); ok && index < 0 {
+		clamped := make([]interface{}, len(values))
+		for i, v := range values {
+			clamped[i] = et.clamp(v)
+		}
+		return clamped
 	}
+	return et.clamp(data)
+}
 
-	if et.FCRead == 0 && (et.BytePosition != 0 || et.BitLength > 0) {
-		return fmt.Errorf("EventType %v is not writable at address %v: can not read data prior to writing", et.ID, et.Address)
+// clamp clamps numeric values via ClampValue and passes all other values through
+func (et *EventType) clamp(v interface{}) interface{} {
+	if f, err := float32Value(v); err == nil {
+		return et.ClampValue(f)
 	}
+	return v
+}
 
+// vWrite reads the block of an EventType, encodes data into it and writes it back. It returns the written block.
+// If index is not negative, only that element of a block-factored EventType is encoded and written.
+// The write is passed on to OnWrite.
+func (o *Device) vWrite(et *EventType, index int, data interface{}, origin WriteOrigin) (b []byte, err error) {
 	o.cmdWLock.Lock()
 	defer o.cmdWLock.Unlock()
+	o.cmdLock.Lock()
+	defer o.cmdLock.Unlock()
 
-	//TODO: Chunked writes
-	step := et.BlockLength
-	if et.BlockFactor > 0 {
-		step = et.BlockLength / et.BlockFactor
+	old, b, err := o.prepareWrite(et, index, data)
+	if err == nil {
+		err = o.writeBlock(et, b, index)
 	}
+	o.recordWrite(origin, et, index, old, b, err)
+	return b, err
+}
 
-	cmd := FsmCmd{ID: NewUUID(), Command: et.FCRead, Address: addr2Bytes(et.Address), ResultLen: byte(step)}
-	var res FsmResult
-	b := []byte{}
-	for i := uint8(0); i < et.BlockLength; i += step {
+// prepareWrite reads the block of an EventType and encodes data into a copy of it, the caller must hold cmdLock.
+// EventTypes which can't be read start from a zeroed block, old is nil then.
+func (o *Device) prepareWrite(et *EventType, index int, data interface{}) (old, b []byte, err error) {
+	err = o.checkWrite(et)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		cmd.Address = addr2Bytes(et.Address + AddressT(i))
-		res = o.RawCmd(cmd)
-		b = append(b, res.Body...)
+	if et.FCRead == 0 && (et.BytePosition != 0 || et.BitLength > 0 || index >= 0) {
+		return nil, nil, fmt.Errorf("EventType %v is not writable at address %v: can not read data prior to writing", et.ID, et.Address)
+	}
 
-		if res.Err != nil {
-			return res.Err
+	if index >= 0 && (et.Elements() == 1 || index >= et.Elements()) {
+		return nil, nil, fmt.Errorf("EventType %v has no element %v", et.ID, index)
+	}
+
+	if et.FCRead != 0 {
+		// A counter may be corrected relative to its current value, which must not be stale
+		old, err = o.readBlock(et, !isRelativeCodec(et.Codec))
+		if err != nil {
+			return nil, nil, err
 		}
+		b = append([]byte{}, old...)
+	} else {
+		b = make([]byte, et.BlockLength)
+	}
+
+	if index >= 0 {
+		err = et.encodeIndex(&b, index, data)
+	} else {
+		err = et.encodeBlock(&b, data)
 	}
-	err = et.Codec.Encode(et, &b, data)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
+	return old, b, nil
+}
 
-	cmd.Command = et.FCWrite
+// WritePlan shows what writing a value to an EventType would send to the device
+type WritePlan struct {
+	ID      string      `json:"id"`
+	Address AddressT    `json:"address"`          // Address of the first byte written
+	Old     HexBytes    `json:"old"`              // Bytes at the address, empty if the EventType can't be read
+	New     HexBytes    `json:"new"`              // Bytes to be written
+	Before  interface{} `json:"before,omitempty"` // Decoded value of Old
+	After   interface{} `json:"after"`            // Decoded value of New
+}
+
+// PlanWrite does everything VWriteIndex does, but instead of writing it returns what would be written.
+// An index of -1 plans writing the whole EventType.
+func (o *Device) PlanWrite(ID string, index int, data interface{}) (plan WritePlan, err error) {
+	et, ok := o.DataPoint().EventType(ID)
+	if !ok {
+		return plan, fmt.Errorf("EventType %v not found", ID)
+	}
+
+	o.cmdLock.Lock()
+	old, b, err := o.prepareWrite(et, index, data)
+	o.cmdLock.Unlock()
+	if err != nil {
+		return plan, err
+	}
+
+	return et.plan(index, old, b)
+}
+
+// PlanWriteClamped works like PlanWrite, clamping the value like VWriteIndexClamped
+func (o *Device) PlanWriteClamped(ID string, index int, data interface{}) (plan WritePlan, err error) {
+	et, ok := o.DataPoint().EventType(ID)
+	if !ok {
+		return plan, fmt.Errorf("EventType %v not found", ID)
+	}
+	return o.PlanWrite(ID, index, et.clampData(index, data))
+}
+
+// step returns the number of bytes read or written per command, which is the size of an element for
+// block-factored EventTypes
+func (et *EventType) step() uint8 {
+	if et.BlockFactor > 0 {
+		return et.BlockLength / et.BlockFactor
+	}
+	return et.BlockLength
+}
+
+// readBlock reads the whole block of an EventType, the caller must hold cmdLock
+func (o *Device) readBlock(et *EventType, useCache bool) (b []byte, err error) {
+	step := et.step()
+	cmd := FsmCmd{ID: NewUUID(), Command: et.FCRead, ResultLen: byte(step), Prefix: et.PrefixRead}
 	for i := uint8(0); i < et.BlockLength; i += step {
 		cmd.Address = addr2Bytes(et.Address + AddressT(i))
-		cmd.Args = b[i : i+step]
-		res = o.RawCmd(cmd)
+		res := o.rawCmds(useCache, cmd)[0]
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		b = append(b, res.Body...)
 	}
+	return b, nil
+}
 
-	return err
+// writeBlock writes the block b of an EventType, the caller must hold cmdLock.
+// If index is not negative, only the chunk holding that element is written.
+func (o *Device) writeBlock(et *EventType, b []byte, index int) error {
+	if len(b) < int(et.BlockLength) {
+		return fmt.Errorf("EventType %v: block of %v bytes is too short", et.ID, len(b))
+	}
+	step := et.step()
+	cmd := FsmCmd{ID: NewUUID(), Command: et.FCWrite, ResultLen: byte(step), Prefix: et.PrefixWrite}
+	for i := uint8(0); i < et.BlockLength; i += step {
+		if index >= 0 && int(i) != index*int(step) {
+			// Only the chunk holding the element has changed
+			continue
+		}
+		cmd.Address = addr2Bytes(et.Address + AddressT(i))
+		cmd.Args = b[i : i+step]
+		res := o.rawCmds(false, cmd)[0]
+		if res.Err != nil {
+			return fmt.Errorf("writing EventType %v at address %v failed: %w", et.ID, et.Address+AddressT(i), res.Err)
+		}
+	}
+	return nil
 }
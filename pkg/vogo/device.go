@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -20,13 +21,19 @@ type Device struct {
 	rlock, wlock sync.Mutex
 
 	link      string
-	connected bool
+	connected atomic.Bool
+	protocol  atomic.Int32
 	Done      chan struct{}
 
-	DataPoint     *DataPointType
+	dataPoint     atomic.Pointer[DataPointType]
+	writePolicy   atomic.Pointer[WritePolicy]
 	Mem           *MemMap
 	CacheDuration time.Duration
 
+	// OnWrite is called for every write to the device, successful or not, e.g. to keep an audit log.
+	// It may be called while writes are locked, so it should return quickly. Set it before writing.
+	OnWrite func(WriteRecord)
+
 	cmdChan  chan FsmCmd
 	resChan  chan FsmResult
 	cmdLock  sync.Mutex
@@ -45,17 +52,22 @@ func (o *Device) Close() error {
 	defer o.rlock.Unlock()
 	defer o.wlock.Unlock()
 
+	if o.Done == nil {
+		// Never connected
+		return io.ErrClosedPipe
+	}
+
 	select {
 	case <-o.Done:
 		// return fmt.Errorf("Close failed: Closing")
-		o.connected = false
+		o.connected.Store(false)
 		return io.ErrClosedPipe
 	default:
 	}
 	o.r.Reset(o.conn) // TODO: check if useful
 	err = o.conn.Close()
 	close(o.Done)
-	o.connected = false
+	o.connected.Store(false)
 	return err
 }
 
@@ -63,13 +75,13 @@ func (o *Device) Read(b []byte) (int, error) {
 	o.rlock.Lock()
 	defer o.rlock.Unlock()
 
-	if !o.connected {
+	if !o.connected.Load() {
 		return 0, io.EOF
 	}
 
 	select {
 	case <-o.Done:
-		o.connected = false
+		o.connected.Store(false)
 		return 0, io.EOF
 	default:
 		n, err := o.r.Read(b)
@@ -82,12 +94,12 @@ func (o *Device) Read(b []byte) (int, error) {
 func (o *Device) ReadByte() (byte, error) {
 	o.rlock.Lock()
 	defer o.rlock.Unlock()
-	if !o.connected {
+	if !o.connected.Load() {
 		return 0, io.EOF
 	}
 	select {
 	case <-o.Done:
-		o.connected = false
+		o.connected.Store(false)
 		return 0, io.EOF
 	default:
 		return o.r.ReadByte()
@@ -98,12 +110,12 @@ func (o *Device) ReadByte() (byte, error) {
 func (o *Device) Peek(n int) ([]byte, error) {
 	o.rlock.Lock()
 	defer o.rlock.Unlock()
-	if !o.connected {
+	if !o.connected.Load() {
 		return nil, io.EOF
 	}
 	select {
 	case <-o.Done:
-		o.connected = false
+		o.connected.Store(false)
 		return nil, io.EOF
 	default:
 		return o.r.Peek(n)
@@ -113,12 +125,12 @@ func (o *Device) Peek(n int) ([]byte, error) {
 func (o *Device) Write(b []byte) (int, error) {
 	o.wlock.Lock()
 	defer o.wlock.Unlock()
-	if !o.connected {
+	if !o.connected.Load() {
 		return 0, io.EOF
 	}
 	select {
 	case <-o.Done:
-		o.connected = false
+		o.connected.Store(false)
 		return 0, io.EOF
 	default:
 		n, err := o.conn.Write(b)
@@ -127,6 +139,26 @@ func (o *Device) Write(b []byte) (int, error) {
 	}
 }
 
+// Connected returns true while the Optolink connection is up
+func (o *Device) Connected() bool {
+	return o.connected.Load()
+}
+
+// Protocol returns the protocol currently spoken on the Optolink connection
+func (o *Device) Protocol() Protocol {
+	return Protocol(o.protocol.Load())
+}
+
+// DataPoint returns the DataPoint of the device along with its EventTypes
+func (o *Device) DataPoint() *DataPointType {
+	return o.dataPoint.Load()
+}
+
+// SetDataPoint replaces the DataPoint, e.g. after reloading the definitions. Requests in flight keep using the previous one.
+func (o *Device) SetDataPoint(dp *DataPointType) {
+	o.dataPoint.Store(dp)
+}
+
 // NewDevice is the factory method to create a new Device
 func NewDevice() *Device {
 	o := &Device{}
@@ -134,8 +166,7 @@ func NewDevice() *Device {
 	o.cmdChan = make(chan FsmCmd)
 	o.resChan = make(chan FsmResult)
 
-	o.DataPoint = &DataPointType{}
-	o.DataPoint.EventTypes = make(EventTypeList)
+	o.dataPoint.Store(&DataPointType{EventTypes: make(EventTypeList)})
 	m := make(MemMap, (1 << 16))
 	o.Mem = &m
 
@@ -152,9 +183,12 @@ func (o *Device) Connect(link string) error {
 	defer o.wlock.Unlock()
 	var err error
 
+	// Remember the link even if connecting fails, so Reconnect can retry
+	o.link = link
+
 	u, err := url.Parse(link)
 	if err != nil {
-		o.connected = false
+		o.connected.Store(false)
 		return err
 	}
 
@@ -173,11 +207,10 @@ func (o *Device) Connect(link string) error {
 			return err
 		}
 	} else {
-		o.connected = false
+		o.connected.Store(false)
 		return fmt.Errorf("can not find a valid connection string in \"%v\"", link)
 	}
-	o.connected = true
-	o.link = link
+	o.connected.Store(true)
 
 	o.Done = make(chan struct{})
 	o.r = bufio.NewReader(o.conn)
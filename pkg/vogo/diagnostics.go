@@ -0,0 +1,109 @@
+package vogo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DiagnosticKind classifies problems found in EventType definitions
+type DiagnosticKind string
+
+const (
+	DiagInvalidAddress        DiagnosticKind = "invalid_address"
+	DiagUnknownAccessMode     DiagnosticKind = "unknown_access_mode"
+	DiagInvalidField          DiagnosticKind = "invalid_field"
+	DiagBlockLengthMismatch   DiagnosticKind = "block_length_mismatch"
+	DiagUnsupportedConversion DiagnosticKind = "unsupported_conversion"
+	DiagInvalidDefault        DiagnosticKind = "invalid_default"
+	DiagMissingDefinition     DiagnosticKind = "missing_definition"
+)
+
+// Diagnostic describes a problem found in the definition of an EventType
+type Diagnostic struct {
+	EventType string         `json:"event_type"`
+	Kind      DiagnosticKind `json:"kind"`
+	Fatal     bool           `json:"fatal"` // The EventType can't be used
+	Message   string         `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	level := "warning"
+	if d.Fatal {
+		level = "error"
+	}
+	return fmt.Sprintf("%s: %s: %s", d.EventType, level, d.Message)
+}
+
+// Diagnostics is a list of problems found in EventType definitions
+type Diagnostics []Diagnostic
+
+// Fatal returns true if any of the problems makes an EventType unusable
+func (ds Diagnostics) Fatal() bool {
+	for _, d := range ds {
+		if d.Fatal {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns an error holding the messages of the fatal problems, nil if there are none
+func (ds Diagnostics) Err() error {
+	var msgs []string
+	for _, d := range ds {
+		if d.Fatal {
+			msgs = append(msgs, d.Message)
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(msgs, "; "))
+}
+
+// invalidEventType returns the placeholder kept for an EventType whose raw definition xet is unusable
+func invalidEventType(vet EventType, xet xEventType) *EventType {
+	return &EventType{ID: vet.ID, Diagnostics: vet.Diagnostics, invalid: &xet}
+}
+
+// Diagnostics returns the problems found in the definitions of the EventTypes of the DataPoint, sorted by EventType ID
+func (dp *DataPointType) Diagnostics() Diagnostics {
+	ds := Diagnostics{}
+	for id, et := range dp.EventTypes {
+		ds = append(ds, et.Diagnostics...)
+		if et.Codec == nil && len(et.Diagnostics) == 0 {
+			ds = append(ds, Diagnostic{EventType: id, Kind: DiagMissingDefinition, Fatal: true, Message: fmt.Sprintf("no definition found for EventType %v", id)})
+		}
+	}
+	sort.SliceStable(ds, func(i, j int) bool { return ds[i].EventType < ds[j].EventType })
+	return ds
+}
+
+// LintEventTypes checks all EventTypes in xml in a format similar to VitoSofts ecnEventType.xml format
+// and returns the problems found, sorted by EventType ID
+func LintEventTypes(xmlReader io.Reader) Diagnostics {
+	decoder := xml.NewDecoder(xmlReader)
+	ds := Diagnostics{}
+
+	for {
+		t, _ := decoder.Token()
+		if t == nil {
+			break
+		}
+		if se, ok := t.(xml.StartElement); ok && se.Name.Local == "EventType" {
+			var x xEventType
+			decoder.DecodeElement(&x, &se)
+
+			// Strip address off the name
+			x.ID = strings.Split(x.ID, "~0x")[0]
+
+			vet, _ := validatexEventType(x)
+			ds = append(ds, vet.Diagnostics...)
+		}
+	}
+	sort.SliceStable(ds, func(i, j int) bool { return ds[i].EventType < ds[j].EventType })
+	return ds
+}
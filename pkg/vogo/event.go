@@ -1,7 +1,10 @@
 package vogo
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
 	"time"
 )
 
@@ -11,6 +14,15 @@ type DataPointType struct {
 	Description    string          `json:"description,omitempty"`
 	SysDeviceIdent SysDeviceIdentT `json:"sys_device_ident"`
 	EventTypes     EventTypeList   `json:"-"`
+
+	Aliases EventTypeAliasList `json:"-"`
+
+	announced map[string]bool // IDs of the EventTypes listed in the DataPoint definition
+}
+
+// Announces returns true if the EventType is listed in the DataPoint definition, even if no valid definition of it was found
+func (dp *DataPointType) Announces(ID string) bool {
+	return dp.announced[ID]
 }
 
 // SysDeviceIdentT holds the full system id of a device (type, hardware revision, software revision, ...)
@@ -20,15 +32,52 @@ func (sdi SysDeviceIdentT) MarshalJSON() ([]byte, error) {
 	return []byte(fmt.Sprintf("\"% X\"", sdi)), nil
 }
 
+// HexBytes is raw data, shown as hex bytes like "01 2C" in JSON
+type HexBytes []byte
+
+func (h HexBytes) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("\"% X\"", []byte(h))), nil
+}
+
+func (h *HexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	*h, err = parseHexBytes(s)
+	return err
+}
+
+// ParseSysDeviceIdent parses an ident given as hex bytes like "20 92 01 07 00 00 01 5A".
+// Idents shorter than 8 bytes are padded with zeros, at least group and device must be given.
+func ParseSysDeviceIdent(s string) (sdi SysDeviceIdentT, err error) {
+	b, err := parseHexBytes(s)
+	if err != nil {
+		return sdi, fmt.Errorf("can't parse ident '%v': %v", s, err)
+	}
+	if len(b) < 2 || len(b) > len(sdi) {
+		return sdi, fmt.Errorf("can't parse ident '%v': need 2 to %v bytes", s, len(sdi))
+	}
+	copy(sdi[:], b)
+	return sdi, nil
+}
+
 // EventType holds low-level info for commands like address, data format and conversion hints
 type EventType struct {
 	ID          string   `json:"id"`
 	Address     AddressT `json:"address"`
 	Description string   `json:"description,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
 
 	FCRead  CommandType `json:"fcread"`
 	FCWrite CommandType `json:"fcwrite"`
 
+	ReadMode  string `json:"read_mode,omitempty"`  // Access mode as named in ecnEventType.xml
+	WriteMode string `json:"write_mode,omitempty"` // Access mode as named in ecnEventType.xml
+	Access    Access `json:"access"`
+	Writable  bool   `json:"writable"` // Access under the WritePolicy of the Device, set in copies made by Device.WithWritable
+
 	Parameter string `json:"-"` // `json:"parameter"`
 
 	PrefixRead   []byte `json:"-"` // `json:"prefix_read,omitempty"`
@@ -41,7 +90,8 @@ type EventType struct {
 	BitPosition  uint8  `json:"bit_position,omitempty"`
 	BitLength    uint8  `json:"bit_length,omitempty"`
 
-	ALZ string `json:"-"` //`json:"alz,omitempty"` // AuslieferZuStand
+	ALZ     string      `json:"alz,omitempty"`     // AuslieferZuStand
+	Default interface{} `json:"default,omitempty"` // ALZ decoded via Codec, nil if there is no usable ALZ
 
 	Conversion string `json:"-"` // `json:"conversion,omitempty"`
 
@@ -49,6 +99,7 @@ type EventType struct {
 	ConversionOffset float32 `json:"conversion_offset,omitempty"`
 	LowerBorder      float32 `json:"lower_border,omitempty"`
 	UpperBorder      float32 `json:"upper_border,omitempty"`
+	Stepping         float32 `json:"stepping,omitempty"`
 
 	ValueList string `json:"value_list,omitempty"` // TODO: save as map[string]string or even map[uint32]string?
 	Unit      string `json:"unit,omitempty"`
@@ -56,6 +107,121 @@ type EventType struct {
 	Codec Codec `json:"codec"`
 
 	Value EventValueType `json:"value,omitempty"`
+
+	Source string `json:"source,omitempty"` // Set if the definition was changed or added by an Overlay
+
+	Diagnostics Diagnostics `json:"diagnostics,omitempty"` // Problems found in the definition
+
+	invalid *xEventType // Raw definition of an unusable EventType, kept to be fixed by an Overlay
+}
+
+// stepTolerance is the tolerance used when checking values against the stepping, as borders and steps are float32
+const stepTolerance = 1e-3
+
+// RangeError is returned when a value does not fit into the borders or the stepping of an EventType
+type RangeError struct {
+	ID          string
+	Value       float32
+	LowerBorder float32
+	UpperBorder float32
+	Stepping    float32
+}
+
+func (e *RangeError) Error() string {
+	s := fmt.Sprintf("value %v not allowed for EventType %v", e.Value, e.ID)
+	if e.LowerBorder != e.UpperBorder {
+		s += fmt.Sprintf(", allowed range is %v..%v", e.LowerBorder, e.UpperBorder)
+	}
+	if e.Stepping > 0 {
+		s += fmt.Sprintf(" in steps of %v", e.Stepping)
+	}
+	return s
+}
+
+func (et *EventType) hasBorders() bool {
+	return et.LowerBorder != et.UpperBorder
+}
+
+// stepBase returns the value from which steps are counted
+func (et *EventType) stepBase() float64 {
+	if et.hasBorders() {
+		return float64(et.LowerBorder)
+	}
+	return 0
+}
+
+// signed returns true if Parameter denotes a signed data type
+func (et *EventType) signed() bool {
+	switch et.Parameter {
+	case "SByte", "SInt", "SIntHighByteFirst", "SInt4":
+		return true
+	}
+	return false
+}
+
+// CheckValue checks if a value lies within the borders of the EventType and matches its stepping
+func (et *EventType) CheckValue(f float32) error {
+	ok := true
+	if et.hasBorders() && (f < et.LowerBorder || f > et.UpperBorder) {
+		ok = false
+	}
+	if ok && et.Stepping > 0 {
+		n := (float64(f) - et.stepBase()) / float64(et.Stepping)
+		ok = math.Abs(n-math.Round(n)) <= stepTolerance
+	}
+	if !ok {
+		return &RangeError{ID: et.ID, Value: f, LowerBorder: et.LowerBorder, UpperBorder: et.UpperBorder, Stepping: et.Stepping}
+	}
+	return nil
+}
+
+// ClampValue rounds a value to the nearest step and clamps it to the borders of the EventType
+func (et *EventType) ClampValue(f float32) float32 {
+	base := et.stepBase()
+	step := float64(et.Stepping)
+
+	if step > 0 {
+		f = float32(base + math.Round((float64(f)-base)/step)*step)
+	}
+	if et.hasBorders() {
+		if f < et.LowerBorder {
+			f = et.LowerBorder
+		}
+		if f > et.UpperBorder {
+			f = et.UpperBorder
+			if step > 0 {
+				// UpperBorder may not be a multiple of the stepping
+				f = float32(base + math.Floor((float64(f)-base)/step+stepTolerance)*step)
+			}
+		}
+	}
+	return f
+}
+
+// Access describes the direction of accesses an access mode or an EventType allows
+type Access byte
+
+const (
+	AccessNone      Access = 0x00
+	AccessRead      Access = 0x01
+	AccessWrite     Access = 0x02
+	AccessReadWrite Access = 0x03
+)
+
+func (a Access) String() string {
+	switch a {
+	case AccessRead:
+		return "read"
+	case AccessWrite:
+		return "write"
+	case AccessReadWrite:
+		return "readwrite"
+	}
+	return "none"
+}
+
+func (a Access) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("\"%s\"", a)), nil
 }
 
 // AddressT is the type for addresses. It is introduced as an alias fpr uint16 to be able to implement MarshalJSON
@@ -70,6 +236,27 @@ type EventValueType interface{}
 // EventTypeList is just a map of EventTyp (aka command) elements
 type EventTypeList map[string]*EventType
 
+// Diff compares the EventTypes with those of next and returns the sorted IDs of added, removed and changed EventTypes
+func (etl EventTypeList) Diff(next EventTypeList) (added, removed, changed []string) {
+	for id, et := range next {
+		prev, ok := etl[id]
+		if !ok {
+			added = append(added, id)
+		} else if prev.def() != et.def() || prev.Source != et.Source || (prev.Codec == nil) != (et.Codec == nil) {
+			changed = append(changed, id)
+		}
+	}
+	for id := range etl {
+		if _, ok := next[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
 // EventTypeAliasList may hold aliases or translated names for commands
 type EventTypeAliasList map[string]*EventType
 
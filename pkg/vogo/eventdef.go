@@ -0,0 +1,210 @@
+package vogo
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// EventTypeDef is the native definition format of an EventType, mirroring the fields of ecnEventType.xml.
+// Definitions are read from YAML or JSON, see DefinitionFile.
+type EventTypeDef struct {
+	ID          string `json:"id" yaml:"id"`
+	Address     string `json:"address" yaml:"address"` // Hex like "0x0800" or decimal
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	Read  string `json:"read,omitempty" yaml:"read,omitempty"`   // Access mode as named in ecnEventType.xml, e.g. "Virtual_READ"
+	Write string `json:"write,omitempty" yaml:"write,omitempty"` // Access mode as named in ecnEventType.xml, e.g. "Virtual_WRITE"
+
+	Parameter   string `json:"parameter,omitempty" yaml:"parameter,omitempty"` // Data type like "Byte", "SInt", "Array"
+	PrefixRead  string `json:"prefix_read,omitempty" yaml:"prefix_read,omitempty"`
+	PrefixWrite string `json:"prefix_write,omitempty" yaml:"prefix_write,omitempty"`
+
+	BlockLength  uint8 `json:"block_length" yaml:"block_length"`
+	BlockFactor  uint8 `json:"block_factor,omitempty" yaml:"block_factor,omitempty"`
+	MappingType  uint8 `json:"mapping_type,omitempty" yaml:"mapping_type,omitempty"`
+	BytePosition uint8 `json:"byte_position,omitempty" yaml:"byte_position,omitempty"`
+	ByteLength   uint8 `json:"byte_length" yaml:"byte_length"`
+	BitPosition  uint8 `json:"bit_position,omitempty" yaml:"bit_position,omitempty"`
+	BitLength    uint8 `json:"bit_length,omitempty" yaml:"bit_length,omitempty"`
+
+	ALZ string `json:"alz,omitempty" yaml:"alz,omitempty"` // AuslieferZuStand
+
+	Conversion       string  `json:"conversion" yaml:"conversion"` // Like in ecnEventType.xml, e.g. "Div10" or "NoConversion"
+	ConversionFactor float32 `json:"conversion_factor,omitempty" yaml:"conversion_factor,omitempty"`
+	ConversionOffset float32 `json:"conversion_offset,omitempty" yaml:"conversion_offset,omitempty"`
+	LowerBorder      float32 `json:"lower_border,omitempty" yaml:"lower_border,omitempty"`
+	UpperBorder      float32 `json:"upper_border,omitempty" yaml:"upper_border,omitempty"`
+	Stepping         float32 `json:"stepping,omitempty" yaml:"stepping,omitempty"`
+
+	ValueList string `json:"value_list,omitempty" yaml:"value_list,omitempty"` // Like "0=Aus;1=An"
+	Unit      string `json:"unit,omitempty" yaml:"unit,omitempty"`
+}
+
+// DefinitionFile is the layout of a file holding EventTypeDefs:
+//
+//	datapoint: VScotHO1_72
+//	event_types:
+//	  - id: BedienRTSolltemperaturA1M1
+//	    address: "0x2306"
+//	    read: Virtual_READ
+//	    write: Virtual_WRITE
+//	    parameter: Byte
+//	    block_length: 1
+//	    byte_length: 1
+//	    conversion: NoConversion
+//	    lower_border: 3
+//	    upper_border: 37
+//	    unit: °C
+type DefinitionFile struct {
+	DataPoint  string         `json:"datapoint,omitempty" yaml:"datapoint,omitempty"` // ID of the DataPoint the definitions were made for
+	EventTypes []EventTypeDef `json:"event_types" yaml:"event_types"`
+}
+
+// ReadDefinitionFile reads EventTypeDefs in YAML or JSON format
+func ReadDefinitionFile(r io.Reader) (*DefinitionFile, error) {
+	var f DefinitionFile
+
+	// YAML is a superset of JSON, so both formats are handled by the YAML decoder
+	err := yaml.NewDecoder(r).Decode(&f)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// FindEventTypes works like the package level FindEventTypes, using the EventTypeDefs of the file
+func (f *DefinitionFile) FindEventTypes(etl *EventTypeList) int {
+	found := 0
+	for _, d := range f.EventTypes {
+		if _, ok := (*etl)[d.ID]; !ok {
+			continue
+		}
+		xet := d.xEventType()
+		vet, err := validatexEventType(xet)
+		if err != nil {
+			log.Warnf("Invalid definition of EventType %v: %v", d.ID, err)
+			// Keep a placeholder to report the problems
+			(*etl)[d.ID] = invalidEventType(vet, xet)
+			continue
+		}
+		(*etl)[d.ID] = &vet
+		found++
+	}
+	return found
+}
+
+func formatFloat(f float32) string {
+	return strconv.FormatFloat(float64(f), 'g', -1, 32)
+}
+
+// xEventType converts the EventTypeDef to the raw xml representation, so it is validated the same way
+func (d EventTypeDef) xEventType() xEventType {
+	u := func(i uint8) string { return strconv.Itoa(int(i)) }
+	x := xEventType{
+		ID:               d.ID,
+		Address:          d.Address,
+		Description:      d.Description,
+		FCRead:           d.Read,
+		FCWrite:          d.Write,
+		Parameter:        d.Parameter,
+		PrefixRead:       d.PrefixRead,
+		PrefixWrite:      d.PrefixWrite,
+		BlockLength:      u(d.BlockLength),
+		BlockFactor:      u(d.BlockFactor),
+		MappingType:      u(d.MappingType),
+		BytePosition:     u(d.BytePosition),
+		ByteLength:       u(d.ByteLength),
+		BitPosition:      u(d.BitPosition),
+		BitLength:        u(d.BitLength),
+		ALZ:              d.ALZ,
+		Conversion:       d.Conversion,
+		ConversionFactor: formatFloat(d.ConversionFactor),
+		ConversionOffset: formatFloat(d.ConversionOffset),
+		LowerBorder:      formatFloat(d.LowerBorder),
+		UpperBorder:      formatFloat(d.UpperBorder),
+		Stepping:         formatFloat(d.Stepping),
+		ValueList:        d.ValueList,
+		Unit:             d.Unit,
+	}
+	if x.FCRead == "" {
+		x.FCRead = "undefined"
+	}
+	if x.FCWrite == "" {
+		x.FCWrite = "undefined"
+	}
+	return x
+}
+
+// eventTypeDef converts a raw xml EventType to an EventTypeDef. Unparsable numbers are left at 0.
+func eventTypeDef(x xEventType) EventTypeDef {
+	u := func(s string) uint8 {
+		i, _ := strconv.ParseUint(s, 0, 8)
+		return uint8(i)
+	}
+	f := func(s string) float32 {
+		f, _ := strconv.ParseFloat(s, 32)
+		return float32(f)
+	}
+	d := EventTypeDef{
+		ID:               strings.Split(x.ID, "~0x")[0],
+		Address:          x.Address,
+		Description:      x.Description,
+		Read:             x.FCRead,
+		Write:            x.FCWrite,
+		Parameter:        x.Parameter,
+		PrefixRead:       x.PrefixRead,
+		PrefixWrite:      x.PrefixWrite,
+		BlockLength:      u(x.BlockLength),
+		BlockFactor:      u(x.BlockFactor),
+		MappingType:      u(x.MappingType),
+		BytePosition:     u(x.BytePosition),
+		ByteLength:       u(x.ByteLength),
+		BitPosition:      u(x.BitPosition),
+		BitLength:        u(x.BitLength),
+		ALZ:              x.ALZ,
+		Conversion:       x.Conversion,
+		ConversionFactor: f(x.ConversionFactor),
+		ConversionOffset: f(x.ConversionOffset),
+		LowerBorder:      f(x.LowerBorder),
+		UpperBorder:      f(x.UpperBorder),
+		Stepping:         f(x.Stepping),
+		ValueList:        x.ValueList,
+		Unit:             x.Unit,
+	}
+	if d.Read == "undefined" {
+		d.Read = ""
+	}
+	if d.Write == "undefined" {
+		d.Write = ""
+	}
+	return d
+}
+
+// ExportEventTypeDefs converts the EventTypes of a DataPoint from xml in a format similar to VitoSofts ecnEventType.xml
+// format into a DefinitionFile. The DataPoint is usually found via FindDataPointType or FindDataPointTypeByID.
+func ExportEventTypeDefs(xmlReader io.Reader, dpt *DataPointType) *DefinitionFile {
+	decoder := xml.NewDecoder(xmlReader)
+	f := &DefinitionFile{DataPoint: dpt.ID, EventTypes: []EventTypeDef{}}
+
+	for {
+		t, _ := decoder.Token()
+		if t == nil {
+			break
+		}
+		if se, ok := t.(xml.StartElement); ok && se.Name.Local == "EventType" {
+			var x xEventType
+			decoder.DecodeElement(&x, &se)
+
+			d := eventTypeDef(x)
+			if _, ok := dpt.EventTypes[d.ID]; ok {
+				f.EventTypes = append(f.EventTypes, d)
+			}
+		}
+	}
+	return f
+}
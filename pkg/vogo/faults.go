@@ -0,0 +1,82 @@
+package vogo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FaultCode describes a code of the error history
+type FaultCode struct {
+	Description string `json:"description"`
+	Severity    string `json:"severity,omitempty"`
+	Action      string `json:"action,omitempty"`
+}
+
+// FaultCatalog maps codes of the error history to their descriptions
+type FaultCatalog map[byte]FaultCode
+
+// LoadFaultCatalog reads a fault-code catalogue in JSON format. Codes may be given in hex or decimal notation:
+//
+//	{
+//	    "0x10": {"description": "Kurzschluss Außentemperatursensor", "severity": "error", "action": "Sensor prüfen"},
+//	    ...
+//	}
+func LoadFaultCatalog(r io.Reader) (FaultCatalog, error) {
+	var m map[string]FaultCode
+
+	err := json.NewDecoder(r).Decode(&m)
+	if err != nil {
+		return nil, err
+	}
+
+	c := make(FaultCatalog, len(m))
+	for k, f := range m {
+		i, err := strconv.ParseUint(k, 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fault code '%v': %v", k, err)
+		}
+		c[byte(i)] = f
+	}
+	return c, nil
+}
+
+// SetFaultCatalog makes the error history EventTypes describe their entries via the given catalogue
+func (etl EventTypeList) SetFaultCatalog(c FaultCatalog) {
+	for _, et := range etl {
+		if _, ok := et.Codec.(MappingErrors); ok {
+			et.Codec = MappingErrors{Catalog: c}
+		}
+	}
+}
+
+// FaultHistory reads all FehlerHis* EventTypes and returns their entries, newest first.
+// EventTypes which could not be read are reported by an entry with Error set, after the others.
+func (o *Device) FaultHistory() ([]ErrEntry, error) {
+	var ids []string
+	for id, et := range o.DataPoint().EventTypes {
+		if strings.HasPrefix(id, "FehlerHis") && et.FCRead != nop {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	entries := []ErrEntry{}
+	var failed []ErrEntry
+	for _, id := range ids {
+		v, err := o.VRead(id)
+		if err != nil {
+			failed = append(failed, ErrEntry{EventType: id, Error: err.Error()})
+			continue
+		}
+		if e, ok := v.([]ErrEntry); ok {
+			entries = append(entries, e...)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.After(entries[j].Date) })
+	return append(entries, failed...), nil
+}
@@ -75,8 +75,89 @@ const (
 	physicalBeWrite         CommandType = 0x9d
 	physicalKmbusRAMRead    CommandType = 0x33
 	physicalKmBusEepromRead CommandType = 0x43
+
+	// P300 function codes for accesses to the MarktManager and WILO pump address spaces
+	p300VirtualMarktManagerRead  CommandType = 0x22
+	p300VirtualMarktManagerWrite CommandType = 0x23
+	p300VirtualWiloRead          CommandType = 0x24
+	p300VirtualWiloWrite         CommandType = 0x25
+
+	// P300 function codes for accesses to modules attached to the KM-Bus, addressed via a prefix
+	p300KbusDataElementRead  CommandType = 0x51
+	p300KbusDataElementWrite CommandType = 0x52
+	p300KbusTransparentRead  CommandType = 0x55
+	p300KbusTransparentWrite CommandType = 0x56
+	p300KbusEepromLtRead     CommandType = 0x59
+	p300KbusEepromLtWrite    CommandType = 0x5a
+	p300KbusMemberListRead   CommandType = 0x5d
+	p300KbusMemberListWrite  CommandType = 0x5e
+	p300KbusVirtualRead      CommandType = 0x5f
+	p300KbusVirtualWrite     CommandType = 0x60
+	p300KbusDirectRead       CommandType = 0x61
+	p300KbusDirectWrite      CommandType = 0x62
+	p300KbusIndirectRead     CommandType = 0x63
+	p300KbusIndirectWrite    CommandType = 0x64
+	p300KbusGatewayRead      CommandType = 0x65
+	p300KbusGatewayWrite     CommandType = 0x66
 )
 
+// p300ReadCmds and p300WriteCmds hold commands which are only available in the P300 protocol
+var p300ReadCmds = map[CommandType]bool{
+	p300VirtualMarktManagerRead: true,
+	p300VirtualWiloRead:         true,
+	p300KbusDataElementRead:     true,
+	p300KbusTransparentRead:     true,
+	p300KbusEepromLtRead:        true,
+	p300KbusMemberListRead:      true,
+	p300KbusVirtualRead:         true,
+	p300KbusDirectRead:          true,
+	p300KbusIndirectRead:        true,
+	p300KbusGatewayRead:         true,
+}
+var p300WriteCmds = map[CommandType]bool{
+	p300VirtualMarktManagerWrite: true,
+	p300VirtualWiloWrite:         true,
+	p300KbusDataElementWrite:     true,
+	p300KbusTransparentWrite:     true,
+	p300KbusEepromLtWrite:        true,
+	p300KbusMemberListWrite:      true,
+	p300KbusVirtualWrite:         true,
+	p300KbusDirectWrite:          true,
+	p300KbusIndirectWrite:        true,
+	p300KbusGatewayWrite:         true,
+}
+
+// p300FunctionCodes maps KW/GWG command types to their P300 function codes.
+// Command types not contained are sent unchanged.
+var p300FunctionCodes = map[CommandType]byte{
+	kwRead:                  0x01,
+	kwWrite:                 0x02,
+	virtualRead:             0x01,
+	virtualWrite:            0x02,
+	physicalRead:            0x03,
+	physicalWrite:           0x04,
+	eepromRead:              0x05,
+	eepromWrite:             0x06,
+	physicalXramRead:        0x31,
+	physicalXramWrite:       0x32,
+	physicalPortRead:        0x33,
+	physicalPortWrite:       0x34,
+	physicalBeRead:          0x35,
+	physicalBeWrite:         0x36,
+	physicalKmbusRAMRead:    0x41,
+	physicalKmBusEepromRead: 0x43,
+}
+
+// virtualCmds access the virtual address space, which is the only one covered by the cache
+var virtualCmds = map[CommandType]bool{
+	p300ReadData:  true,
+	p300WriteData: true,
+	kwRead:        true,
+	kwWrite:       true,
+	virtualRead:   true,
+	virtualWrite:  true,
+}
+
 var readCmds = map[CommandType]bool{
 	p300ReadData:            true,
 	kwRead:                  true,
@@ -103,9 +184,12 @@ var writeCmds = map[CommandType]bool{
 const maxFail int = 100
 
 func isReadCmd(c CommandType) bool {
-	if _, ok := readCmds[c]; ok {
+	if readCmds[c] || p300ReadCmds[c] {
 		return true
 	}
+	if writeCmds[c] || p300WriteCmds[c] {
+		return false
+	}
 	if (c & 0x1f) == p300ReadData {
 		return true
 	}
@@ -113,15 +197,60 @@ func isReadCmd(c CommandType) bool {
 }
 
 func isWriteCmd(c CommandType) bool {
-	if _, ok := writeCmds[c]; ok {
+	if writeCmds[c] || p300WriteCmds[c] {
 		return true
 	}
+	if readCmds[c] || p300ReadCmds[c] {
+		return false
+	}
 	if (c & 0x1f) == p300WriteData {
 		return true
 	}
 	return false
 }
 
+// p300FunctionCode returns the P300 function code for a command type
+func p300FunctionCode(c CommandType) byte {
+	if fc, ok := p300FunctionCodes[c]; ok {
+		return fc
+	}
+	return byte(c)
+}
+
+// Protocol is the protocol spoken on the Optolink connection
+type Protocol int32
+
+const (
+	ProtocolUnknown Protocol = iota
+	ProtocolKW
+	ProtocolP300
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolKW:
+		return "KW"
+	case ProtocolP300:
+		return "P300"
+	}
+	return "unknown"
+}
+
+func (p Protocol) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("\"%s\"", p)), nil
+}
+
+// cmdSupported returns true if a command type can be sent via the given protocol
+func cmdSupported(c CommandType, p Protocol) bool {
+	if c == nop || c == p300FunctionCall {
+		return false
+	}
+	if p == ProtocolKW {
+		return !p300ReadCmds[c] && !p300WriteCmds[c]
+	}
+	return true
+}
+
 // FsmCmd holds a command for the VitoFsm state machine
 type FsmCmd struct {
 	ID        [16]byte // uuid.UID
@@ -129,6 +258,7 @@ type FsmCmd struct {
 	Address   [2]byte
 	Args      []byte
 	ResultLen byte
+	Prefix    []byte // Sent in front of the address, e.g. to address modules on the KM-Bus
 }
 
 // FsmResult is the result type for a previously issued FsmCmd command
@@ -158,24 +288,32 @@ func prepareCmd(cmd *FsmCmd, state VitoState) (b []byte, err error) {
 		} else if cmd.Command == kwWrite {
 			cmd.Command = p300WriteData
 		}
+		fc := p300FunctionCode(cmd.Command)
 
-		switch cmd.Command {
-		case p300ReadData:
-			b = []byte{0x41, byte(5), 0x00, 0x01, cmd.Address[0], cmd.Address[1], cmd.ResultLen}
-		case p300WriteData:
-			b = []byte{0x41, byte((len(cmd.Args) + 5)), 0x00, 0x02, cmd.Address[0], cmd.Address[1], byte(len(cmd.Args))}
-			b = append(b, cmd.Args...)
-			cmd.ResultLen = byte(len(cmd.Args))
-		case p300FunctionCall:
+		switch {
+		case cmd.Command == p300FunctionCall:
 			// b = []byte{0x41, byte((len(cmd.Args) + 5)), 0x00, 0x07, cmd.Address[0], cmd.Address[1], cmd.ResultLen}
 			err = fmt.Errorf("not implemented: p300FunctionCall")
 			return nil, err
+		case cmd.Command == nop:
+			err = fmt.Errorf("not implemented: %v (GWG protocol?)", cmd.Command)
+			return nil, err
+		case isReadCmd(cmd.Command):
+			b = []byte{0x41, byte(len(cmd.Prefix) + 5), 0x00, fc}
+			b = append(b, cmd.Prefix...)
+			b = append(b, cmd.Address[0], cmd.Address[1], cmd.ResultLen)
+		case isWriteCmd(cmd.Command):
+			b = []byte{0x41, byte(len(cmd.Prefix) + len(cmd.Args) + 5), 0x00, fc}
+			b = append(b, cmd.Prefix...)
+			b = append(b, cmd.Address[0], cmd.Address[1], byte(len(cmd.Args)))
+			b = append(b, cmd.Args...)
+			cmd.ResultLen = byte(len(cmd.Args))
 		default:
 			err = fmt.Errorf("not implemented: %v (GWG protocol?)", cmd.Command)
 			return nil, err
 		}
 
-		if useSeqCnt {
+		if useSeqCnt && fc < 0x20 {
 			// Introduce command sequence counter bits as VitoConnect does
 			b[3] = b[3] | (cmdSeq << 5)
 			cmdSeq++
@@ -194,10 +332,16 @@ func prepareCmd(cmd *FsmCmd, state VitoState) (b []byte, err error) {
 			cmd.Command = kwWrite
 		}
 
-		switch cmd.Command {
-		case kwRead:
+		if len(cmd.Prefix) > 0 || !cmdSupported(cmd.Command, ProtocolKW) {
+			err = fmt.Errorf("not implemented: %v (GWG protocol, P300 function call or prefixed/KM-Bus access needing P300?)", cmd.Command)
+			return nil, err
+		}
+
+		// KW sends the GWG command types as they are
+		switch {
+		case isReadCmd(cmd.Command):
 			b = []byte{byte(cmd.Command), cmd.Address[0], cmd.Address[1], cmd.ResultLen}
-		case kwWrite:
+		case isWriteCmd(cmd.Command):
 			b = []byte{byte(cmd.Command), cmd.Address[0], cmd.Address[1], byte(len(cmd.Args))}
 			b = append(b, cmd.Args...)
 			cmd.ResultLen = 1
@@ -226,6 +370,7 @@ func (device *Device) vitoFsm() (err error) { //, peer *io.ReadWriter, inChan <-
 
 	defer func() {
 		log.Warnf("Exiting vitoFSM (err: %v)", err)
+		device.protocol.Store(int32(ProtocolUnknown))
 		device.Done <- struct{}{}
 	}()
 
@@ -433,6 +578,7 @@ func (device *Device) vitoFsm() (err error) { //, peer *io.ReadWriter, inChan <-
 				lastEnq = time.Now()
 			}
 		case sendKwStart:
+			device.protocol.Store(int32(ProtocolKW))
 			if prevstate != recvKw {
 				_, err := device.Write([]byte{0x01})
 				if err != nil {
@@ -468,10 +614,10 @@ func (device *Device) vitoFsm() (err error) { //, peer *io.ReadWriter, inChan <-
 				state = idle
 				break
 			}
-			if cmd.Command == kwWrite {
+			if isWriteCmd(cmd.Command) {
 				// Should return 0x00 on successful write
 				if b[0] != 0x00 {
-					err = fmt.Errorf("kwWrite returned %v, expected 0x00", b)
+					err = fmt.Errorf("%v returned %v, expected 0x00", cmd.Command, b)
 					log.Error(err)
 					device.resChan <- FsmResult{cmd.ID, err, nil}
 					state = idle
@@ -513,6 +659,9 @@ func (device *Device) vitoFsm() (err error) { //, peer *io.ReadWriter, inChan <-
 			if err != nil {
 				return err
 			}
+			if state == wait {
+				device.protocol.Store(int32(ProtocolP300))
+			}
 
 			lastSyn = time.Now()
 		case wait:
@@ -627,8 +776,19 @@ func (device *Device) vitoFsm() (err error) { //, peer *io.ReadWriter, inChan <-
 				break
 			}
 			// cmd.Command & 0x1F to strip the sequence counting bits in some protocol implementations
-			if (telegramPart2[1] & 0x1F) != byte(cmd.Command) {
-				err = fmt.Errorf("wrong command byte (expected %x, received %x)", telegramPart2[1], byte(cmd.Command))
+			fc := telegramPart2[1]
+			if p300FunctionCode(cmd.Command) < 0x20 {
+				fc = fc & 0x1F
+			}
+			if fc != p300FunctionCode(cmd.Command) {
+				err = fmt.Errorf("wrong command byte (expected %x, received %x)", p300FunctionCode(cmd.Command), telegramPart2[1])
+				device.resChan <- FsmResult{cmd.ID, err, nil}
+				break
+			}
+			// Answers mirror the request, including a prefix
+			p := len(cmd.Prefix)
+			if len(telegramPart2) < p+6 {
+				err = fmt.Errorf("telegram too short (%v bytes)", len(telegramPart2))
 				device.resChan <- FsmResult{cmd.ID, err, nil}
 				break
 			}
@@ -645,16 +805,16 @@ func (device *Device) vitoFsm() (err error) { //, peer *io.ReadWriter, inChan <-
 				err = fmt.Errorf("received error telegram instead of an answer")
 			}
 
-			if telegramPart2[4] != cmd.ResultLen {
-				err = fmt.Errorf("expected result length %x != received length %x", cmd.ResultLen, telegramPart2[4])
+			if telegramPart2[p+4] != cmd.ResultLen {
+				err = fmt.Errorf("expected result length %x != received length %x", cmd.ResultLen, telegramPart2[p+4])
 			}
 
-			if cmd.Command != p300WriteData {
+			if !isWriteCmd(cmd.Command) {
 				// Return data in Body
-				device.resChan <- FsmResult{ID: cmd.ID, Err: err, Body: telegram[6 : len(telegram)-1]}
+				device.resChan <- FsmResult{ID: cmd.ID, Err: err, Body: telegram[p+6 : len(telegram)-1]}
 			} else {
 				// Return number of written bytes in Body
-				device.resChan <- FsmResult{ID: cmd.ID, Err: err, Body: []byte{telegram[5]}}
+				device.resChan <- FsmResult{ID: cmd.ID, Err: err, Body: []byte{telegram[p+5]}}
 			}
 			state = recvP300Ack
 		case recvP300Ack:
@@ -0,0 +1,109 @@
+package vogo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// DeviceIdent is the decoded form of a SysDeviceIdent
+type DeviceIdent struct {
+	Group              uint8  `json:"group"`                // sysDeviceGroupIdent
+	Device             uint8  `json:"device"`               // sysDeviceIdent
+	HardwareIndex      uint8  `json:"hardware_index"`       // sysHardwareIndex
+	SoftwareIndex      uint8  `json:"software_index"`       // sysSoftwareIndex
+	ProtocolVersionLDA uint8  `json:"protocol_version_lda"` // sysProtocolVersionLDA
+	ProtocolVersionRDA uint8  `json:"protocol_version_rda"` // sysProtocolVersionRDA
+	DeveloperVersion   uint16 `json:"developer_version"`    // sysDeveloperVersion
+}
+
+// Decode splits the ident into its parts
+func (sdi SysDeviceIdentT) Decode() DeviceIdent {
+	return DeviceIdent{
+		Group:              sdi[0],
+		Device:             sdi[1],
+		HardwareIndex:      sdi[2],
+		SoftwareIndex:      sdi[3],
+		ProtocolVersionLDA: sdi[4],
+		ProtocolVersionRDA: sdi[5],
+		DeveloperVersion:   uint16(sdi[6])<<8 | uint16(sdi[7]),
+	}
+}
+
+func (di DeviceIdent) String() string {
+	return fmt.Sprintf("group %02X, device %02X, hardware index %02X, software index %02X, protocol LDA %02X, RDA %02X, developer version %04X",
+		di.Group, di.Device, di.HardwareIndex, di.SoftwareIndex, di.ProtocolVersionLDA, di.ProtocolVersionRDA, di.DeveloperVersion)
+}
+
+// DataPointCandidate is a DataPoint with the Identification of a device, along with the result of matching it
+type DataPointCandidate struct {
+	ID                          string `json:"id"`
+	Description                 string `json:"description,omitempty"`
+	Identification              string `json:"identification"`
+	IdentificationExtension     string `json:"identification_extension,omitempty"`
+	IdentificationExtensionTill string `json:"identification_extension_till,omitempty"`
+
+	Matches bool   `json:"matches"` // The ident lies within the extension range
+	Chosen  bool   `json:"chosen"`  // The DataPoint FindDataPointType returns
+	Reason  string `json:"reason"`
+}
+
+// dataPointCandidates matches the DataPoints against the ident the same way FindDataPointType does, explaining the outcome
+func dataPointCandidates(dps []xDataPointType, sysDeviceIdent [8]byte) []DataPointCandidate {
+	var best xDataPointType
+	for _, d := range dps {
+		if betterDataPointMatch(d, best, sysDeviceIdent) {
+			best = d
+		}
+	}
+
+	cs := []DataPointCandidate{}
+	for _, d := range dps {
+		if i, ok := dataPointIdent(d); !ok || i != uint16(sysDeviceIdent[0])<<8|uint16(sysDeviceIdent[1]) {
+			continue
+		}
+		c := DataPointCandidate{
+			ID:                          d.ID,
+			Description:                 d.Description,
+			Identification:              d.Identification,
+			IdentificationExtension:     d.IdentificationExtension,
+			IdentificationExtensionTill: d.IdentificationExtensionTill,
+		}
+		c.Matches, c.Reason = matchDataPoint(d, sysDeviceIdent)
+		if c.Matches {
+			if d.ID == best.ID {
+				c.Chosen = true
+				c.Reason = "narrowest matching extension range"
+			} else {
+				c.Reason = fmt.Sprintf("matches, but the extension range of %v is narrower", best.ID)
+			}
+		}
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+// FindDataPointCandidates lists all DataPoints with the Identification of the device from xml in a format similar to
+// VitoSofts ecnDataPointType.xml format, with the reason why each was chosen or rejected
+func FindDataPointCandidates(xmlReader io.Reader, sysDeviceIdent [8]byte) []DataPointCandidate {
+	var dps []xDataPointType
+	decoder := xml.NewDecoder(xmlReader)
+
+	for {
+		t, _ := decoder.Token()
+		if t == nil {
+			break
+		}
+		if se, ok := t.(xml.StartElement); ok && se.Name.Local == "DataPointType" {
+			var d xDataPointType
+			decoder.DecodeElement(&d, &se)
+			dps = append(dps, d)
+		}
+	}
+	return dataPointCandidates(dps, sysDeviceIdent)
+}
+
+// FindDataPointCandidates works like the package level FindDataPointCandidates, using the bundle instead of xml
+func (b *DefinitionBundle) FindDataPointCandidates(sysDeviceIdent [8]byte) []DataPointCandidate {
+	return dataPointCandidates(b.DataPoints[uint16(sysDeviceIdent[0])<<8|uint16(sysDeviceIdent[1])], sysDeviceIdent)
+}
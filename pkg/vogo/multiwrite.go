@@ -0,0 +1,213 @@
+package vogo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// WriteStatus tells what happened to a single EventType written with VWriteMulti
+type WriteStatus string
+
+const (
+	WriteDone           WriteStatus = "written"
+	WriteSkipped        WriteStatus = "not_written"     // Nothing was written, as another EventType failed before
+	WriteRolledBack     WriteStatus = "rolled_back"     // The previous value has been restored
+	WriteRollbackFailed WriteStatus = "rollback_failed" // Restoring the previous value failed, the device state is unknown
+)
+
+// WriteResult reports the outcome of writing a single EventType with VWriteMulti
+type WriteResult struct {
+	ID       string      `json:"id"`
+	Value    interface{} `json:"value"`              // Value asked for
+	Previous interface{} `json:"previous,omitempty"` // Value before writing
+	Written  interface{} `json:"written,omitempty"`  // Value read back after writing
+	Status   WriteStatus `json:"status"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// pendingWrite is an EventType in the course of VWriteMulti
+type pendingWrite struct {
+	et       *EventType
+	res      *WriteResult
+	old, new []byte
+	written  bool // At least one chunk may have been written
+}
+
+// VWriteMulti writes several EventTypes as a whole: the current blocks are read, all values are encoded and written,
+// then read back to verify them. If anything fails, the previous blocks are restored. The results are sorted by ID.
+// Each EventType is passed on to OnWrite.
+func (o *Device) VWriteMulti(values map[string]interface{}, origins ...WriteOrigin) ([]WriteResult, error) {
+	ids := make([]string, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	results := make([]WriteResult, len(ids))
+	pws := make([]*pendingWrite, len(ids))
+	dp := o.DataPoint()
+
+	var err error
+	for i, id := range ids {
+		results[i] = WriteResult{ID: id, Value: values[id], Status: WriteSkipped}
+		et, ok := dp.EventType(id)
+		if !ok {
+			results[i].Error = fmt.Sprintf("EventType %v not found", id)
+			err = errors.Join(err, fmt.Errorf("EventType %v not found", id))
+			continue
+		}
+		e := o.checkWrite(et)
+		if e == nil && et.FCRead == 0 {
+			// Without reading, neither rollback nor verification is possible
+			e = fmt.Errorf("EventType %v can't be written with others at address %v: can not read data prior to writing", et.ID, et.Address)
+		}
+		if e != nil {
+			results[i].Error = e.Error()
+			err = errors.Join(err, e)
+			continue
+		}
+		pws[i] = &pendingWrite{et: et, res: &results[i]}
+	}
+	if err != nil {
+		o.recordMulti(origin(origins), results, pws)
+		return results, err
+	}
+
+	o.cmdWLock.Lock()
+	defer o.cmdWLock.Unlock()
+	o.cmdLock.Lock()
+	defer o.cmdLock.Unlock()
+	defer o.recordMulti(origin(origins), results, pws)
+
+	// Read and encode all values before writing anything
+	for _, pw := range pws {
+		pw.old, err = o.readBlock(pw.et, false)
+		if err != nil {
+			return failWrite(results, pw, err)
+		}
+		pw.res.Previous, _ = pw.et.decodeBlock(&pw.old)
+	}
+	planned, failed, err := planWrites(pws)
+	if err != nil {
+		return failWrite(results, failed, err)
+	}
+
+	for _, pw := range pws {
+		pw.written = true
+		err = o.writeBlock(pw.et, pw.new, -1)
+		if err != nil {
+			pw.res.Error = err.Error()
+			return o.rollback(results, pws, err)
+		}
+	}
+
+	// Verify against the final bytes, which may have been changed by a later EventType at the same address
+	for _, pw := range pws {
+		b, err := o.readBlock(pw.et, false)
+		if err != nil {
+			pw.res.Error = err.Error()
+			return o.rollback(results, pws, err)
+		}
+		for i := range pw.new {
+			pw.new[i] = planned[pw.et.Address+AddressT(i)]
+		}
+		if !bytes.Equal(b, pw.new) {
+			err = fmt.Errorf("EventType %v: read back % X instead of % X", pw.et.ID, b, pw.new)
+			pw.res.Error = err.Error()
+			return o.rollback(results, pws, err)
+		}
+		pw.res.Written, _ = pw.et.decodeBlock(&b)
+		pw.res.Status = WriteDone
+	}
+	return results, nil
+}
+
+// recordMulti passes the outcome of VWriteMulti for every EventType on to OnWrite
+func (o *Device) recordMulti(origin WriteOrigin, results []WriteResult, pws []*pendingWrite) {
+	if o.OnWrite == nil {
+		return
+	}
+	for i, res := range results {
+		rec := WriteRecord{ID: res.ID}
+		if pw := pws[i]; pw != nil {
+			plan, _ := pw.et.plan(-1, pw.old, pw.new)
+			rec = rec.withPlan(plan)
+		}
+		var err error
+		if res.Error != "" {
+			err = errors.New(res.Error)
+		} else if res.Status != WriteDone {
+			err = errors.New(string(res.Status))
+		}
+		o.record(origin, rec, err)
+	}
+}
+
+// planWrites encodes the values of the pending writes into copies of their old blocks. EventTypes may share
+// addresses, e.g. bit fields, so each is encoded into the bytes planned by the ones before. It returns the final
+// bytes by address, or the pending write which failed to encode.
+func planWrites(pws []*pendingWrite) (planned map[AddressT]byte, failed *pendingWrite, err error) {
+	planned = make(map[AddressT]byte)
+	for _, pw := range pws {
+		pw.new = make([]byte, len(pw.old))
+		for i := range pw.old {
+			if b, ok := planned[pw.et.Address+AddressT(i)]; ok {
+				pw.new[i] = b
+			} else {
+				pw.new[i] = pw.old[i]
+			}
+		}
+		err = pw.et.encodeBlock(&pw.new, pw.res.Value)
+		if err != nil {
+			return nil, pw, err
+		}
+		for i, b := range pw.new {
+			planned[pw.et.Address+AddressT(i)] = b
+		}
+	}
+	return planned, nil, nil
+}
+
+// failWrite records err for the pending write which failed before anything was written
+func failWrite(results []WriteResult, pw *pendingWrite, err error) ([]WriteResult, error) {
+	pw.res.Error = err.Error()
+	return results, fmt.Errorf("nothing written: %w", err)
+}
+
+// rollback restores and verifies the previous blocks of all EventTypes written so far. As all blocks were read before
+// writing, they hold the original bytes even where EventTypes share addresses. The caller must hold cmdLock.
+func (o *Device) rollback(results []WriteResult, pws []*pendingWrite, cause error) ([]WriteResult, error) {
+	errs := []error{cause}
+	for i := len(pws) - 1; i >= 0; i-- {
+		pw := pws[i]
+		if !pw.written {
+			continue
+		}
+		pw.res.Written = nil
+
+		// A refused write leaves the block unchanged, there is nothing to restore then
+		b, err := o.readBlock(pw.et, false)
+		if err != nil || !bytes.Equal(b, pw.old) {
+			err = o.writeBlock(pw.et, pw.old, -1)
+			if err == nil {
+				b, err = o.readBlock(pw.et, false)
+				if err == nil && !bytes.Equal(b, pw.old) {
+					err = fmt.Errorf("EventType %v: read back % X instead of % X", pw.et.ID, b, pw.old)
+				}
+			}
+		}
+		if err != nil {
+			pw.res.Status = WriteRollbackFailed
+			if pw.res.Error != "" {
+				pw.res.Error += "; "
+			}
+			pw.res.Error += "rollback failed: " + err.Error()
+			errs = append(errs, fmt.Errorf("rollback failed: %w", err))
+			continue
+		}
+		pw.res.Status = WriteRolledBack
+	}
+	return results, errors.Join(errs...)
+}
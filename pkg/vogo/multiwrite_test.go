@@ -0,0 +1,92 @@
+package vogo
+
+import (
+	"bytes"
+	"testing"
+)
+
+// bitEventType returns an EventType for a bit field in the byte at addr
+func bitEventType(id string, addr AddressT, pos, n uint8) *EventType {
+	return &EventType{ID: id, Address: addr, Parameter: "Byte", BlockLength: 1, ByteLength: 1, BitPosition: pos, BitLength: n,
+		ConversionFactor: 1, Codec: DivMulOffsetCodec{}}
+}
+
+func TestPlanWrites(t *testing.T) {
+	word := &EventType{ID: "Word", Address: 0x2000, Parameter: "Int", BlockLength: 2, ByteLength: 2, ConversionFactor: 1, Codec: DivMulOffsetCodec{}}
+
+	tests := []struct {
+		name    string
+		writes  []*pendingWrite
+		want    map[AddressT]byte
+		failed  int // index of the write failing to encode, -1 if none
+		wantNew [][]byte
+	}{
+		{
+			name: "separate addresses",
+			writes: []*pendingWrite{
+				{et: bitEventType("A", 0x1000, 0, 1), old: []byte{0xF0}, res: &WriteResult{Value: 1}},
+				{et: word, old: []byte{0x00, 0x00}, res: &WriteResult{Value: 0x0102}},
+			},
+			want:    map[AddressT]byte{0x1000: 0xF1, 0x2000: 0x02, 0x2001: 0x01},
+			failed:  -1,
+			wantNew: [][]byte{{0xF1}, {0x02, 0x01}},
+		},
+		{
+			name: "bit fields sharing a byte",
+			writes: []*pendingWrite{
+				{et: bitEventType("Low", 0x1000, 4, 4), old: []byte{0x00}, res: &WriteResult{Value: 5}},
+				{et: bitEventType("High", 0x1000, 0, 4), old: []byte{0x00}, res: &WriteResult{Value: 10}},
+			},
+			want:    map[AddressT]byte{0x1000: 0xA5},
+			failed:  -1,
+			wantNew: [][]byte{{0x05}, {0xA5}},
+		},
+		{
+			name: "byte overlapping a word",
+			writes: []*pendingWrite{
+				{et: word, old: []byte{0x11, 0x22}, res: &WriteResult{Value: 0x3344}},
+				{et: &EventType{ID: "High", Address: 0x2001, Parameter: "Byte", BlockLength: 1, ByteLength: 1, ConversionFactor: 1, Codec: DivMulOffsetCodec{}},
+					old: []byte{0x22}, res: &WriteResult{Value: 0x55}},
+			},
+			want:    map[AddressT]byte{0x2000: 0x44, 0x2001: 0x55},
+			failed:  -1,
+			wantNew: [][]byte{{0x44, 0x33}, {0x55}},
+		},
+		{
+			name: "value not fitting",
+			writes: []*pendingWrite{
+				{et: bitEventType("Low", 0x1000, 4, 4), old: []byte{0x00}, res: &WriteResult{Value: 5}},
+				{et: bitEventType("High", 0x1000, 0, 4), old: []byte{0x00}, res: &WriteResult{Value: 16}},
+			},
+			failed: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planned, failed, err := planWrites(tt.writes)
+			if tt.failed >= 0 {
+				if err == nil || failed != tt.writes[tt.failed] {
+					t.Fatalf("got %v, failed write %v, want write %v to fail", err, failed, tt.failed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(planned) != len(tt.want) {
+				t.Errorf("planned %v addresses, want %v", len(planned), len(tt.want))
+			}
+			for addr, b := range tt.want {
+				if planned[addr] != b {
+					t.Errorf("planned %02X at 0x%04X, want %02X", planned[addr], uint16(addr), b)
+				}
+			}
+			for i, pw := range tt.writes {
+				if !bytes.Equal(pw.new, tt.wantNew[i]) {
+					t.Errorf("%v: new block % X, want % X", pw.et.ID, pw.new, tt.wantNew[i])
+				}
+			}
+		})
+	}
+}
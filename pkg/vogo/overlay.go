@@ -0,0 +1,157 @@
+package vogo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Overlay holds local changes to EventType definitions, applied after the EventTypes of a DataPoint have been found.
+// It is read from YAML or JSON:
+//
+//	patch:                          # change fields of existing EventTypes, using the field names of EventTypeDef
+//	  BedienRTSolltemperaturA1M1:
+//	    byte_position: 0
+//	    conversion_factor: 0.5
+//	add:                            # add EventTypes for undocumented addresses, see EventTypeDef
+//	  - id: Undocumented_Temperature
+//	    address: "0x0810"
+//	    ...
+//	remove:                         # remove EventTypes which should not be accessible
+//	  - Codieradresse_A0
+type Overlay struct {
+	Patch  map[string]yaml.Node `yaml:"patch"`
+	Add    []EventTypeDef       `yaml:"add"`
+	Remove []string             `yaml:"remove"`
+}
+
+// ReadOverlay reads an Overlay in YAML or JSON format
+func ReadOverlay(r io.Reader) (*Overlay, error) {
+	var ov Overlay
+
+	err := yaml.NewDecoder(r).Decode(&ov)
+	if err != nil {
+		return nil, err
+	}
+	return &ov, nil
+}
+
+// Apply removes, patches and adds EventTypes, in that order. Changed EventTypes get source as Source.
+// Patched EventTypes are validated again, so patches may fix invalid definitions. A conversion_factor patched into
+// an EventType with a fixed factor conversion like Div10 turns it into MultOffset, so the patched factor is used.
+// Changes which can't be applied are skipped, their errors are returned combined.
+func (ov *Overlay) Apply(etl EventTypeList, source string) error {
+	var errs []error
+
+	for _, id := range ov.Remove {
+		if _, ok := etl[id]; !ok {
+			errs = append(errs, fmt.Errorf("can't remove EventType %v: not found", id))
+			continue
+		}
+		delete(etl, id)
+	}
+
+	for id, node := range ov.Patch {
+		et, ok := etl[id]
+		if !ok {
+			errs = append(errs, fmt.Errorf("can't patch EventType %v: not found", id))
+			continue
+		}
+		// Unusable EventTypes, e.g. with a wrong BytePosition, are patched from their raw definition
+		d := et.def()
+		if et.invalid != nil {
+			d = eventTypeDef(*et.invalid)
+		}
+		err := node.Decode(&d)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("can't patch EventType %v: %w", id, err))
+			continue
+		}
+		d.ID = id
+		if hasKey(&node, "conversion_factor") && fixedFactor(d.Conversion) {
+			// Conversions like Div10 set their own factor, so the patched factor would be lost
+			d.Conversion = "MultOffset"
+		}
+		vet, err := validatexEventType(d.xEventType())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("can't patch EventType %v: %w", id, err))
+			continue
+		}
+		vet.Aliases = et.Aliases
+		vet.Source = source
+		etl[id] = &vet
+	}
+
+	for _, d := range ov.Add {
+		if _, ok := etl[d.ID]; ok {
+			errs = append(errs, fmt.Errorf("can't add EventType %v: already defined, use patch instead", d.ID))
+			continue
+		}
+		vet, err := validatexEventType(d.xEventType())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("can't add EventType %v: %w", d.ID, err))
+			continue
+		}
+		vet.Source = source
+		etl[d.ID] = &vet
+	}
+
+	return errors.Join(errs...)
+}
+
+// hasKey returns true if the YAML mapping node has the key
+func hasKey(node *yaml.Node, key string) bool {
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
+
+// fixedFactor returns true for conversions like Div10 or Mult2, which set the ConversionFactor themselves
+func fixedFactor(conversion string) bool {
+	for _, prefix := range []string{"Div", "Mult"} {
+		n := strings.TrimPrefix(conversion, prefix)
+		if n != conversion && n != "" && strings.Trim(n, "0123456789") == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// def converts an EventType back to an EventTypeDef
+func (et *EventType) def() EventTypeDef {
+	return EventTypeDef{
+		ID:               et.ID,
+		Address:          fmt.Sprintf("0x%04X", uint16(et.Address)),
+		Description:      et.Description,
+		Read:             et.ReadMode,
+		Write:            et.WriteMode,
+		Parameter:        et.Parameter,
+		PrefixRead:       strings.ToUpper(fmt.Sprintf("%x", et.PrefixRead)),
+		PrefixWrite:      strings.ToUpper(fmt.Sprintf("%x", et.PrefixWrite)),
+		BlockLength:      et.BlockLength,
+		BlockFactor:      et.BlockFactor,
+		MappingType:      et.MappingType,
+		BytePosition:     et.BytePosition,
+		ByteLength:       et.ByteLength,
+		BitPosition:      et.BitPosition,
+		BitLength:        et.BitLength,
+		ALZ:              et.ALZ,
+		Conversion:       et.Conversion,
+		ConversionFactor: et.ConversionFactor,
+		ConversionOffset: et.ConversionOffset,
+		LowerBorder:      et.LowerBorder,
+		UpperBorder:      et.UpperBorder,
+		Stepping:         et.Stepping,
+		ValueList:        et.ValueList,
+		Unit:             et.Unit,
+	}
+}
@@ -0,0 +1,108 @@
+package vogo
+
+import (
+	"strings"
+	"testing"
+)
+
+const overlayTestXML = `<?xml version="1.0"?>
+<DocumentElement>
+<EventType><ID>Soll~0x2306</ID><Address>0x2306</Address><FCRead>Virtual_READ</FCRead><FCWrite>Virtual_WRITE</FCWrite><Parameter>Byte</Parameter><BlockLength>1</BlockLength><ByteLength>1</ByteLength><BlockFactor>0</BlockFactor><MappingType>0</MappingType><BytePosition>1</BytePosition><BitPosition>0</BitPosition><BitLength>0</BitLength><Conversion>NoConversion</Conversion></EventType>
+</DocumentElement>`
+
+func TestOverlayPatchInvalid(t *testing.T) {
+	etl := EventTypeList{"Soll": nil}
+	if found := FindEventTypes(strings.NewReader(overlayTestXML), &etl); found != 0 {
+		t.Fatalf("found %v valid EventTypes, want 0", found)
+	}
+	if !etl["Soll"].Diagnostics.Fatal() {
+		t.Fatalf("EventType with wrong BytePosition is not reported as invalid")
+	}
+
+	ov, err := ReadOverlay(strings.NewReader("patch:\n  Soll:\n    byte_position: 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ov.Apply(etl, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	et := etl["Soll"]
+	if et.Codec == nil || et.Address != 0x2306 || et.BlockLength != 1 || et.BytePosition != 0 || et.FCWrite == 0 {
+		t.Fatalf("patched EventType lost its definition: %+v", et)
+	}
+	if et.Diagnostics.Fatal() || et.Source != "test" {
+		t.Errorf("patched EventType: diagnostics %v, source %q", et.Diagnostics, et.Source)
+	}
+
+	b := []byte{0x15}
+	v, err := et.decodeBlock(&b)
+	if err != nil || v != float32(21) {
+		t.Errorf("decoding patched EventType: got %v (%T), %v", v, v, err)
+	}
+}
+
+func TestOverlayPatchStillInvalid(t *testing.T) {
+	etl := EventTypeList{"Soll": nil}
+	FindEventTypes(strings.NewReader(overlayTestXML), &etl)
+
+	ov, err := ReadOverlay(strings.NewReader("patch:\n  Soll:\n    unit: K\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ov.Apply(etl, "test"); err == nil {
+		t.Fatalf("patch leaving the BytePosition mismatch was applied")
+	}
+	et := etl["Soll"]
+	if !et.Diagnostics.Fatal() || et.invalid == nil {
+		t.Errorf("placeholder lost its diagnostics or raw definition: %+v", et)
+	}
+}
+
+func TestOverlayPatchConversionFactor(t *testing.T) {
+	const xml = `<?xml version="1.0"?>
+<DocumentElement>
+<EventType><ID>Temp~0x0800</ID><Address>0x0800</Address><FCRead>Virtual_READ</FCRead><Parameter>SInt</Parameter><BlockLength>2</BlockLength><ByteLength>2</ByteLength><BlockFactor>0</BlockFactor><MappingType>0</MappingType><BytePosition>0</BytePosition><BitPosition>0</BitPosition><BitLength>0</BitLength><Conversion>Div10</Conversion></EventType>
+</DocumentElement>`
+
+	for _, tt := range []struct {
+		patch      string
+		conversion string
+		want       float32
+	}{
+		{"conversion_factor: 0.5", "MultOffset", 50},
+		{"unit: K", "Div10", 10},
+		{"conversion: Div100", "Div100", 1},
+	} {
+		etl := EventTypeList{"Temp": nil}
+		if found := FindEventTypes(strings.NewReader(xml), &etl); found != 1 {
+			t.Fatalf("found %v valid EventTypes, want 1", found)
+		}
+		ov, err := ReadOverlay(strings.NewReader("patch:\n  Temp:\n    " + tt.patch + "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ov.Apply(etl, "test"); err != nil {
+			t.Fatalf("%v: %v", tt.patch, err)
+		}
+
+		et := etl["Temp"]
+		b := []byte{0x64, 0x00}
+		v, err := et.decodeBlock(&b)
+		if et.Conversion != tt.conversion || err != nil || v != tt.want {
+			t.Errorf("%v: conversion %v, decoding % X gave %v, %v, want %v, %v", tt.patch, et.Conversion, b, v, err, tt.conversion, tt.want)
+		}
+	}
+}
+
+func TestFixedFactor(t *testing.T) {
+	for conversion, want := range map[string]bool{
+		"Div10": true, "Div2": true, "Mult5": true, "Mult100": true,
+		"MultOffset": false, "MultOffsetBCD": false, "Div": false, "NoConversion": false, "Sec2Hour": false,
+	} {
+		if got := fixedFactor(conversion); got != want {
+			t.Errorf("fixedFactor(%v) = %v, want %v", conversion, got, want)
+		}
+	}
+}
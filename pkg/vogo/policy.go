@@ -0,0 +1,100 @@
+package vogo
+
+import (
+	"fmt"
+	"path"
+)
+
+// WritePolicy restricts which EventTypes may be written. It is enforced by all write methods of the Device.
+type WritePolicy struct {
+	ReadOnly bool     // Refuse all writes
+	Allow    []string // Glob patterns like "Bedien*" of writable EventType IDs, all are allowed if empty
+	Deny     []string // Glob patterns of EventType IDs which must not be written, taking precedence over Allow
+
+	Raw []AddressRange // Address ranges open to RawWrite, raw writes are refused if empty
+}
+
+// WriteDeniedError is returned when the WritePolicy forbids writing an EventType
+type WriteDeniedError struct {
+	ID     string // Empty for raw writes
+	Reason string
+}
+
+func (e *WriteDeniedError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("raw write is not allowed: %v", e.Reason)
+	}
+	return fmt.Sprintf("writing EventType %v is not allowed: %v", e.ID, e.Reason)
+}
+
+// Validate checks the glob patterns of the policy
+func (p *WritePolicy) Validate() error {
+	for _, patterns := range [][]string{p.Allow, p.Deny} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// check returns a WriteDeniedError if the policy forbids writing the EventType ID, nil otherwise
+func (p *WritePolicy) check(ID string) error {
+	if p == nil {
+		return nil
+	}
+	if p.ReadOnly {
+		return &WriteDeniedError{ID: ID, Reason: "read-only mode"}
+	}
+	if matchAny(p.Deny, ID) {
+		return &WriteDeniedError{ID: ID, Reason: "denied"}
+	}
+	if len(p.Allow) > 0 && !matchAny(p.Allow, ID) {
+		return &WriteDeniedError{ID: ID, Reason: "not in the allowed EventTypes"}
+	}
+	return nil
+}
+
+func matchAny(patterns []string, ID string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, ID); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SetWritePolicy replaces the WritePolicy of the Device, nil allows writing all writable EventTypes
+func (o *Device) SetWritePolicy(p *WritePolicy) {
+	o.writePolicy.Store(p)
+}
+
+// WritePolicy returns the WritePolicy of the Device, nil if there is none
+func (o *Device) WritePolicy() *WritePolicy {
+	return o.writePolicy.Load()
+}
+
+// checkWrite returns an error if the EventType can't be written, either by definition or by the WritePolicy
+func (o *Device) checkWrite(et *EventType) error {
+	if et.FCWrite == 0 {
+		return fmt.Errorf("EventType %v is not writable at address %v", et.ID, et.Address)
+	}
+	return o.WritePolicy().check(et.ID)
+}
+
+// Writable returns true if the EventType can be written, taking the WritePolicy into account
+func (o *Device) Writable(et *EventType) bool {
+	return o.checkWrite(et) == nil
+}
+
+// WithWritable returns a copy of the EventTypeList with Writable set according to the WritePolicy
+func (o *Device) WithWritable(etl EventTypeList) EventTypeList {
+	l := make(EventTypeList, len(etl))
+	for id, et := range etl {
+		c := *et
+		c.Writable = o.Writable(et)
+		l[id] = &c
+	}
+	return l
+}
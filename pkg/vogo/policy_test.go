@@ -0,0 +1,58 @@
+package vogo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckRawCmds(t *testing.T) {
+	raw := []AddressRange{{From: 0x2300, To: 0x23FF}}
+	write := func(addr AddressT, n int) FsmCmd {
+		return FsmCmd{Command: p300WriteData, Address: addr2Bytes(addr), Args: make([]byte, n), ResultLen: byte(n)}
+	}
+	read := FsmCmd{Command: p300ReadData, Address: addr2Bytes(0x0800), ResultLen: 2}
+
+	tests := []struct {
+		name    string
+		policy  *WritePolicy
+		cmds    []FsmCmd
+		allowed bool
+	}{
+		{"reads without policy", nil, []FsmCmd{read}, true},
+		{"reads in read-only mode", &WritePolicy{ReadOnly: true}, []FsmCmd{read}, true},
+		{"write without policy", nil, []FsmCmd{write(0x2306, 1)}, true},
+		{"function call without policy", nil, []FsmCmd{{Command: p300FunctionCall, Address: addr2Bytes(0x0100)}}, true},
+		{"write without raw ranges", &WritePolicy{}, []FsmCmd{write(0x2306, 1)}, false},
+		{"write in read-only mode", &WritePolicy{ReadOnly: true, Raw: raw}, []FsmCmd{write(0x2306, 1)}, false},
+		{"write within range", &WritePolicy{Raw: raw}, []FsmCmd{write(0x2306, 2)}, true},
+		{"write crossing the range", &WritePolicy{Raw: raw}, []FsmCmd{write(0x23FF, 2)}, false},
+		{"function call outside of range", &WritePolicy{Raw: raw}, []FsmCmd{{Command: p300FunctionCall, Address: addr2Bytes(0x0100)}}, false},
+		{"denied write among reads", &WritePolicy{Raw: raw}, []FsmCmd{read, write(0x0100, 1)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewDevice()
+			o.SetWritePolicy(tt.policy)
+			err := o.checkRawCmds(tt.cmds)
+			if tt.allowed && err != nil {
+				t.Errorf("got %v, want allowed", err)
+			}
+			var deniedErr *WriteDeniedError
+			if !tt.allowed && !errors.As(err, &deniedErr) {
+				t.Errorf("got %v, want WriteDeniedError", err)
+			}
+		})
+	}
+}
+
+func TestRawCmdsDenied(t *testing.T) {
+	o := NewDevice()
+	o.SetWritePolicy(&WritePolicy{ReadOnly: true})
+
+	// Nothing is sent, so this must not wait for the unconnected device
+	ress := o.RawCmds(FsmCmd{ID: [16]byte{1}, Command: p300WriteData, Address: addr2Bytes(0x2306), Args: []byte{1}, ResultLen: 1})
+	if len(ress) != 1 || ress[0].ID != [16]byte{1} || ress[0].Err == nil {
+		t.Errorf("got %+v, want a denied result", ress)
+	}
+}
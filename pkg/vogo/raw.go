@@ -0,0 +1,132 @@
+package vogo
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MaxRawWrite is the largest number of bytes RawWrite writes at once, matching the chunks of RawCmds
+const MaxRawWrite = 32
+
+// AddressRange is a range of addresses, including From and To
+type AddressRange struct {
+	From, To AddressT
+}
+
+// ParseAddressRange parses a range like "0x2300-0x23FF" or a single address like "0x2306"
+func ParseAddressRange(s string) (r AddressRange, err error) {
+	from, to, found := strings.Cut(s, "-")
+	f, err := strconv.ParseUint(strings.TrimSpace(from), 0, 16)
+	if err != nil {
+		return r, fmt.Errorf("invalid address range %q: %w", s, err)
+	}
+	t := f
+	if found {
+		t, err = strconv.ParseUint(strings.TrimSpace(to), 0, 16)
+		if err != nil {
+			return r, fmt.Errorf("invalid address range %q: %w", s, err)
+		}
+	}
+	if t < f {
+		return r, fmt.Errorf("invalid address range %q: end lies before start", s)
+	}
+	return AddressRange{From: AddressT(f), To: AddressT(t)}, nil
+}
+
+func (r AddressRange) String() string {
+	return fmt.Sprintf("0x%04X-0x%04X", uint16(r.From), uint16(r.To))
+}
+
+// contains returns true if all n bytes starting at addr lie within the range
+func (r AddressRange) contains(addr AddressT, n int) bool {
+	return addr >= r.From && int(addr)+n-1 <= int(r.To)
+}
+
+// RawMismatchError is returned by RawWrite if the device does not hold the expected bytes
+type RawMismatchError struct {
+	Address  AddressT
+	Expected []byte
+	Actual   []byte
+}
+
+func (e *RawMismatchError) Error() string {
+	return fmt.Sprintf("address 0x%04X holds % X instead of the expected % X", uint16(e.Address), e.Actual, e.Expected)
+}
+
+// checkRaw returns a WriteDeniedError if the policy forbids writing n bytes at addr. Raw writes are refused
+// unless they lie within one of the Raw ranges.
+func (p *WritePolicy) checkRaw(addr AddressT, n int) error {
+	if p == nil || len(p.Raw) == 0 {
+		return &WriteDeniedError{Reason: "raw writes are disabled"}
+	}
+	if p.ReadOnly {
+		return &WriteDeniedError{Reason: "read-only mode"}
+	}
+	for _, r := range p.Raw {
+		if r.contains(addr, n) {
+			return nil
+		}
+	}
+	return &WriteDeniedError{Reason: fmt.Sprintf("%v bytes at 0x%04X are outside of the writable address ranges", n, uint16(addr))}
+}
+
+// RawWrite writes data to the virtual address space of the device, but only if it currently holds expected
+// (compare-and-swap). The written bytes are read back to verify them. It returns the bytes found before writing.
+// Raw writes must be enabled for the address via the Raw ranges of the WritePolicy.
+func (o *Device) RawWrite(addr AddressT, expected, data []byte, origins ...WriteOrigin) (old []byte, err error) {
+	// The bytes are recorded once the write has been found valid
+	var attempted []byte
+	defer func() {
+		o.record(origin(origins), WriteRecord{Raw: true, Address: addr, Old: old, New: attempted}, err)
+	}()
+
+	if len(data) == 0 || len(data) > MaxRawWrite {
+		return nil, fmt.Errorf("can't write %v bytes at once, 1 to %v are possible", len(data), MaxRawWrite)
+	}
+	if len(expected) != len(data) {
+		return nil, fmt.Errorf("expected %v bytes, but %v bytes to write", len(expected), len(data))
+	}
+	err = o.WritePolicy().checkRaw(addr, len(data))
+	if err != nil {
+		return nil, err
+	}
+	attempted = data
+
+	o.cmdWLock.Lock()
+	defer o.cmdWLock.Unlock()
+	o.cmdLock.Lock()
+	defer o.cmdLock.Unlock()
+
+	read := FsmCmd{ID: NewUUID(), Command: p300ReadData, Address: addr2Bytes(addr), ResultLen: byte(len(data))}
+	res := o.rawCmds(false, read)[0]
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	old = res.Body
+	if !bytes.Equal(old, expected) {
+		return old, &RawMismatchError{Address: addr, Expected: expected, Actual: old}
+	}
+
+	write := FsmCmd{ID: NewUUID(), Command: p300WriteData, Address: addr2Bytes(addr), ResultLen: byte(len(data)), Args: data}
+	res = o.rawCmds(false, write)[0]
+	if res.Err != nil {
+		log.Warnf("Raw write at 0x%04X failed: % X -> % X: %v", uint16(addr), old, data, res.Err)
+		return old, res.Err
+	}
+
+	read.ID = NewUUID()
+	res = o.rawCmds(false, read)[0]
+	if res.Err == nil && !bytes.Equal(res.Body, data) {
+		res.Err = fmt.Errorf("read back % X instead of % X", res.Body, data)
+	}
+	if res.Err != nil {
+		log.Warnf("Raw write at 0x%04X not verified: % X -> % X: %v", uint16(addr), old, data, res.Err)
+		return old, res.Err
+	}
+	log.Infof("Raw write at 0x%04X: % X -> % X", uint16(addr), old, data)
+	return old, nil
+}
@@ -0,0 +1,96 @@
+package vogo
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SettingDiff describes a writable EventType whose current value differs from its factory default
+type SettingDiff struct {
+	ID          string      `json:"id"`
+	Description string      `json:"description,omitempty"`
+	Value       interface{} `json:"value,omitempty"`
+	Default     interface{} `json:"default"`
+	Unit        string      `json:"unit,omitempty"`
+	Error       string      `json:"error,omitempty"` // Set if the current value could not be read
+}
+
+// writable returns true if an EventType can be read and written, which is needed to compare it with or reset it to its default
+func (et *EventType) writable() bool {
+	return et.FCRead != nop && et.FCWrite != nop
+}
+
+// isDefault checks if a value read from the device equals the default of the EventType
+func (et *EventType) isDefault(v interface{}) bool {
+	if values, ok := v.([]interface{}); ok {
+		for _, e := range values {
+			if !valuesEqual(e, et.Default) {
+				return false
+			}
+		}
+		return true
+	}
+	return valuesEqual(v, et.Default)
+}
+
+func valuesEqual(a, b interface{}) bool {
+	fa, errA := float32Value(a)
+	fb, errB := float32Value(b)
+	if errA == nil && errB == nil {
+		return fa == fb
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// SettingsDiff reads all writable EventTypes with a factory default and returns those differing from it, sorted by ID.
+// EventTypes which could not be read are reported with Error set.
+func (o *Device) SettingsDiff() ([]SettingDiff, error) {
+	// The EventTypes might be replaced by a reload meanwhile, stick to one list
+	etl := o.DataPoint().EventTypes
+	var ids []string
+	for id, et := range etl {
+		if et.Default != nil && et.writable() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	diff := []SettingDiff{}
+	for _, id := range ids {
+		et := etl[id]
+		d := SettingDiff{ID: id, Description: et.Description, Default: et.Default, Unit: et.Unit}
+
+		v, err := o.VRead(id)
+		if err != nil {
+			d.Error = err.Error()
+		} else if et.isDefault(v) {
+			continue
+		}
+		d.Value = v
+		diff = append(diff, d)
+	}
+	return diff, nil
+}
+
+// VReset writes the factory default (ALZ) of an EventType. Block-factored EventTypes get the default for every element.
+func (o *Device) VReset(ID string, origins ...WriteOrigin) error {
+	et, ok := o.DataPoint().EventType(ID)
+	if !ok {
+		return fmt.Errorf("EventType %v not found", ID)
+	}
+	if et.Default == nil {
+		return fmt.Errorf("EventType %v has no factory default", ID)
+	}
+
+	var data interface{} = et.Default
+	if n := et.Elements(); n > 1 {
+		values := make([]interface{}, n)
+		for i := range values {
+			values[i] = et.Default
+		}
+		data = values
+	}
+
+	_, err := o.vWrite(et, -1, data, origin(origins))
+	return err
+}
@@ -0,0 +1,92 @@
+package vogo
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// TextResources maps the labels used in ecnEventType.xml to texts of a single language
+type TextResources map[string]string
+
+// LoadTextResources reads texts from xml in a format similar to VitoSofts Textresource_xx.xml format,
+// i.e. from any element with Label and Value attributes:
+//
+//	<TextResource CultureId="1" Label="viessmann.eventtype.name.BedienRTSolltemperaturA1M1" Value="Raumsolltemperatur normal" />
+func LoadTextResources(xmlReader io.Reader) (TextResources, error) {
+	decoder := xml.NewDecoder(xmlReader)
+	tr := make(TextResources)
+
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var label, value string
+		for _, a := range se.Attr {
+			switch a.Name.Local {
+			case "Label":
+				label = a.Value
+			case "Value":
+				value = a.Value
+			}
+		}
+		if label != "" {
+			tr[label] = value
+		}
+	}
+	return tr, nil
+}
+
+// Text returns the text for a label, or the label itself if there is none
+func (tr TextResources) Text(label string) string {
+	if s, ok := tr[label]; ok && s != "" {
+		return s
+	}
+	return label
+}
+
+// localizeValueList translates the labels of a ValueList like "0=label0;1=label1"
+func (tr TextResources) localizeValueList(vl string) string {
+	entries := strings.Split(vl, ";")
+	for i, e := range entries {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) == 2 {
+			entries[i] = kv[0] + "=" + tr.Text(kv[1])
+		}
+	}
+	return strings.Join(entries, ";")
+}
+
+// Localize returns a copy of the EventType with Description, Unit and ValueList labels resolved via the text resources
+func (tr TextResources) Localize(et *EventType) *EventType {
+	l := *et
+	l.Description = tr.Text(et.Description)
+	if l.Description == et.Description {
+		// Fall back to the label Vitosoft derives from the ID
+		l.Description = tr.Text("viessmann.eventtype.name." + et.ID)
+		if l.Description == "viessmann.eventtype.name."+et.ID {
+			l.Description = et.Description
+		}
+	}
+	l.Unit = tr.Text(et.Unit)
+	l.ValueList = tr.localizeValueList(et.ValueList)
+	return &l
+}
+
+// LocalizeList returns a copy of the EventTypeList with all EventTypes localized
+func (tr TextResources) LocalizeList(etl EventTypeList) EventTypeList {
+	l := make(EventTypeList, len(etl))
+	for id, et := range etl {
+		l[id] = tr.Localize(et)
+	}
+	return l
+}
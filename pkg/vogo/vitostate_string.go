@@ -62,31 +62,71 @@ func _() {
 	_ = x[physicalBeWrite-157]
 	_ = x[physicalKmbusRAMRead-51]
 	_ = x[physicalKmBusEepromRead-67]
+	_ = x[p300VirtualMarktManagerRead-34]
+	_ = x[p300VirtualMarktManagerWrite-35]
+	_ = x[p300VirtualWiloRead-36]
+	_ = x[p300VirtualWiloWrite-37]
+	_ = x[p300KbusDataElementRead-81]
+	_ = x[p300KbusDataElementWrite-82]
+	_ = x[p300KbusTransparentRead-85]
+	_ = x[p300KbusTransparentWrite-86]
+	_ = x[p300KbusEepromLtRead-89]
+	_ = x[p300KbusEepromLtWrite-90]
+	_ = x[p300KbusMemberListRead-93]
+	_ = x[p300KbusMemberListWrite-94]
+	_ = x[p300KbusVirtualRead-95]
+	_ = x[p300KbusVirtualWrite-96]
+	_ = x[p300KbusDirectRead-97]
+	_ = x[p300KbusDirectWrite-98]
+	_ = x[p300KbusIndirectRead-99]
+	_ = x[p300KbusIndirectWrite-100]
+	_ = x[p300KbusGatewayRead-101]
+	_ = x[p300KbusGatewayWrite-102]
 }
 
-const _CommandType_name = "nopp300ReadDatap300WriteDatap300FunctionCallphysicalKmbusRAMReadphysicalKmBusEepromReadphysicalPortWritephysicalPortReadphysicalBeWritephysicalBeReadeepromWriteeepromReadphysicalXramWritevirtualWritephysicalXramReadvirtualReadphysicalWritephysicalReadkwWritekwRead"
+const _CommandType_name = "nopp300ReadDatap300WriteDatap300FunctionCallp300VirtualMarktManagerReadp300VirtualMarktManagerWritep300VirtualWiloReadp300VirtualWiloWritephysicalKmbusRAMReadphysicalKmBusEepromReadp300KbusDataElementReadp300KbusDataElementWritep300KbusTransparentReadp300KbusTransparentWritep300KbusEepromLtReadp300KbusEepromLtWritep300KbusMemberListReadp300KbusMemberListWritep300KbusVirtualReadp300KbusVirtualWritep300KbusDirectReadp300KbusDirectWritep300KbusIndirectReadp300KbusIndirectWritep300KbusGatewayReadp300KbusGatewayWritephysicalPortWritephysicalPortReadphysicalBeWritephysicalBeReadeepromWriteeepromReadphysicalXramWritevirtualWritephysicalXramReadvirtualReadphysicalWritephysicalReadkwWritekwRead"
 
 var _CommandType_map = map[CommandType]string{
 	0:   _CommandType_name[0:3],
 	1:   _CommandType_name[3:15],
 	2:   _CommandType_name[15:28],
 	7:   _CommandType_name[28:44],
-	51:  _CommandType_name[44:64],
-	67:  _CommandType_name[64:87],
-	109: _CommandType_name[87:104],
-	110: _CommandType_name[104:120],
-	157: _CommandType_name[120:135],
-	158: _CommandType_name[135:149],
-	173: _CommandType_name[149:160],
-	174: _CommandType_name[160:170],
-	195: _CommandType_name[170:187],
-	196: _CommandType_name[187:199],
-	197: _CommandType_name[199:215],
-	199: _CommandType_name[215:226],
-	200: _CommandType_name[226:239],
-	203: _CommandType_name[239:251],
-	244: _CommandType_name[251:258],
-	247: _CommandType_name[258:264],
+	34:  _CommandType_name[44:71],
+	35:  _CommandType_name[71:99],
+	36:  _CommandType_name[99:118],
+	37:  _CommandType_name[118:138],
+	51:  _CommandType_name[138:158],
+	67:  _CommandType_name[158:181],
+	81:  _CommandType_name[181:204],
+	82:  _CommandType_name[204:228],
+	85:  _CommandType_name[228:251],
+	86:  _CommandType_name[251:275],
+	89:  _CommandType_name[275:295],
+	90:  _CommandType_name[295:316],
+	93:  _CommandType_name[316:338],
+	94:  _CommandType_name[338:361],
+	95:  _CommandType_name[361:380],
+	96:  _CommandType_name[380:400],
+	97:  _CommandType_name[400:418],
+	98:  _CommandType_name[418:437],
+	99:  _CommandType_name[437:457],
+	100: _CommandType_name[457:478],
+	101: _CommandType_name[478:497],
+	102: _CommandType_name[497:517],
+	109: _CommandType_name[517:534],
+	110: _CommandType_name[534:550],
+	157: _CommandType_name[550:565],
+	158: _CommandType_name[565:579],
+	173: _CommandType_name[579:590],
+	174: _CommandType_name[590:600],
+	195: _CommandType_name[600:617],
+	196: _CommandType_name[617:629],
+	197: _CommandType_name[629:645],
+	199: _CommandType_name[645:656],
+	200: _CommandType_name[656:669],
+	203: _CommandType_name[669:681],
+	244: _CommandType_name[681:688],
+	247: _CommandType_name[688:694],
 }
 
 func (i CommandType) String() string {
@@ -51,7 +51,7 @@ type xEventType struct {
 	ConversionOffset string
 	LowerBorder      string
 	UpperBorder      string
-	Stepping         string // TODO: check if this is given implicitely by conversion
+	Stepping         string
 
 	ValueList string
 	Unit      string
@@ -76,80 +76,132 @@ func FindDataPointType(xmlReader io.Reader, sysDeviceIdent [8]byte, dpt *DataPoi
 				var d xDataPointType
 				decoder.DecodeElement(&d, &se)
 
-				// TODO: Should we return any matching device regardless of wether it can be handled via KW or P300?
-				if len(d.Identification) != 4 {
-					break
-				}
-
-				if (len(d.IdentificationExtension) == 0 || (len(d.IdentificationExtension) >= 4 && len(d.IdentificationExtension) <= 6)) &&
-					(len(d.IdentificationExtensionTill) == 0 || (len(d.IdentificationExtensionTill) >= 4 && len(d.IdentificationExtensionTill) <= 6)) {
-
-					i, err := strconv.ParseUint("0x00"+d.Identification, 0, 16)
-					if err != nil {
-						break
-					}
-
-					// Match sysDeviceGroupIdent
-					if sysDeviceIdent[0] != byte((i>>8)&0xff) {
-						break
-					}
-					// Match sysDeviceIdent
-					if sysDeviceIdent[1] != byte(i&0xff) {
-						break
-					}
-
-					idExt, err := strconv.ParseUint("0x00"+d.IdentificationExtension, 0, 24)
-					if err != nil {
-						idExt = 0
-
-					}
-					idExtTill, err := strconv.ParseUint("0x00"+d.IdentificationExtensionTill, 0, 24)
-					if err != nil {
-						idExtTill = 0
-					}
-
-					var dataPointIDExt uint64
-					dataPointIDExt = uint64(sysDeviceIdent[2])<<8 | uint64(sysDeviceIdent[3])
-					if (len(d.IdentificationExtension) > 4) || (len(d.IdentificationExtensionTill) > 4) {
-						dataPointIDExt = uint64(dataPointIDExt)<<16 | uint64(sysDeviceIdent[4])<<8 | uint64(sysDeviceIdent[5])
-					}
-					if dataPointIDExt >= idExt && (dataPointIDExt < idExtTill || idExtTill == 0) {
-						if dp.ID == "" {
-							// First match, break as there is nothing to compare
-							dp = d
-							break
-						}
-
-						dpidExt, err := strconv.ParseUint("0x00"+dp.IdentificationExtension, 0, 24)
-						if err != nil {
-							dpidExt = 0
-						}
-						dpidExtTill, err := strconv.ParseUint("0x00"+dp.IdentificationExtensionTill, 0, 24)
-						if err != nil {
-							dpidExtTill = 0
-						}
-
-						if idExt >= dpidExt && (idExtTill < dpidExtTill || dpidExtTill == 0) {
-							// A better match than the previously found one
-							dp = d
-						}
-					}
+				if betterDataPointMatch(d, dp, sysDeviceIdent) {
+					dp = d
 				}
 			}
 		default:
 			//
 		}
 	}
+	return setDataPointType(dp, sysDeviceIdent, dpt)
+}
+
+// FindDataPointTypeByID reads the info of the DataPoint with the given ID from xml in a format similar to VitoSofts ecnDataPointType.xml format
+func FindDataPointTypeByID(xmlReader io.Reader, ID string, dpt *DataPointType) error {
+	decoder := xml.NewDecoder(xmlReader)
+
+	for {
+		t, _ := decoder.Token()
+		if t == nil {
+			break
+		}
+		if se, ok := t.(xml.StartElement); ok && se.Name.Local == "DataPointType" {
+			var d xDataPointType
+			decoder.DecodeElement(&d, &se)
+
+			if d.ID == ID {
+				var sysDeviceIdent [8]byte
+				if i, ok := dataPointIdent(d); ok {
+					sysDeviceIdent[0], sysDeviceIdent[1] = byte(i>>8), byte(i)
+				}
+				return setDataPointType(d, sysDeviceIdent, dpt)
+			}
+		}
+	}
+	return ErrNotFound
+}
+
+// dataPointIdent returns the sysDeviceGroupIdent and sysDeviceIdent of a DataPoint as uint16, ok is false if it can't be handled
+func dataPointIdent(d xDataPointType) (ident uint16, ok bool) {
+	// TODO: Should we return any matching device regardless of wether it can be handled via KW or P300?
+	if len(d.Identification) != 4 {
+		return 0, false
+	}
+	i, err := strconv.ParseUint("0x00"+d.Identification, 0, 16)
+	if err != nil {
+		return 0, false
+	}
+	return uint16(i), true
+}
+
+// extensionRange returns the IdentificationExtension range of a DataPoint and the ident extension of the device to compare with it
+func extensionRange(d xDataPointType, sysDeviceIdent [8]byte) (idExt, idExtTill, dataPointIDExt uint64) {
+	idExt, err := strconv.ParseUint("0x00"+d.IdentificationExtension, 0, 24)
+	if err != nil {
+		idExt = 0
+
+	}
+	idExtTill, err = strconv.ParseUint("0x00"+d.IdentificationExtensionTill, 0, 24)
+	if err != nil {
+		idExtTill = 0
+	}
+
+	dataPointIDExt = uint64(sysDeviceIdent[2])<<8 | uint64(sysDeviceIdent[3])
+	if (len(d.IdentificationExtension) > 4) || (len(d.IdentificationExtensionTill) > 4) {
+		dataPointIDExt = uint64(dataPointIDExt)<<16 | uint64(sysDeviceIdent[4])<<8 | uint64(sysDeviceIdent[5])
+	}
+	return idExt, idExtTill, dataPointIDExt
+}
+
+// matchDataPoint returns true if d matches sysDeviceIdent, otherwise the reason why not
+func matchDataPoint(d xDataPointType, sysDeviceIdent [8]byte) (bool, string) {
+	i, ok := dataPointIdent(d)
+	if !ok {
+		return false, fmt.Sprintf("Identification '%v' can't be handled", d.Identification)
+	}
+
+	if !((len(d.IdentificationExtension) == 0 || (len(d.IdentificationExtension) >= 4 && len(d.IdentificationExtension) <= 6)) &&
+		(len(d.IdentificationExtensionTill) == 0 || (len(d.IdentificationExtensionTill) >= 4 && len(d.IdentificationExtensionTill) <= 6))) {
+		return false, fmt.Sprintf("IdentificationExtension '%v'..'%v' can't be handled", d.IdentificationExtension, d.IdentificationExtensionTill)
+	}
+
+	// Match sysDeviceGroupIdent and sysDeviceIdent
+	if sysDeviceIdent[0] != byte((i>>8)&0xff) || sysDeviceIdent[1] != byte(i&0xff) {
+		return false, fmt.Sprintf("Identification %04X does not match %02X%02X", i, sysDeviceIdent[0], sysDeviceIdent[1])
+	}
+
+	idExt, idExtTill, dataPointIDExt := extensionRange(d, sysDeviceIdent)
+	if dataPointIDExt < idExt {
+		return false, fmt.Sprintf("extension %X is below IdentificationExtension %X", dataPointIDExt, idExt)
+	}
+	if dataPointIDExt >= idExtTill && idExtTill != 0 {
+		return false, fmt.Sprintf("extension %X is not below IdentificationExtensionTill %X", dataPointIDExt, idExtTill)
+	}
+	return true, ""
+}
+
+// betterDataPointMatch returns true if d matches sysDeviceIdent and is a better match than the previously found dp
+func betterDataPointMatch(d, dp xDataPointType, sysDeviceIdent [8]byte) bool {
+	if ok, _ := matchDataPoint(d, sysDeviceIdent); !ok {
+		return false
+	}
+	if dp.ID == "" {
+		// First match, nothing to compare
+		return true
+	}
+
+	idExt, idExtTill, _ := extensionRange(d, sysDeviceIdent)
+	dpidExt, dpidExtTill, _ := extensionRange(dp, sysDeviceIdent)
+
+	// A better match than the previously found one
+	return idExt >= dpidExt && (idExtTill < dpidExtTill || dpidExtTill == 0)
+}
+
+// setDataPointType fills dpt with the info of the matching DataPoint dp and prepares an EventTypeList of the announced EventTypes
+func setDataPointType(dp xDataPointType, sysDeviceIdent [8]byte, dpt *DataPointType) error {
 	if dp.ID != "" {
 		r := dpt
 		r.ID = dp.ID
 		r.Description = dp.Description
 		r.SysDeviceIdent = sysDeviceIdent
 		etl := dpt.EventTypes
+		r.announced = make(map[string]bool)
 
 		for _, et := range strings.Split(dp.EventtTypeList, ";") {
 			et = strings.Split(et, "~0x")[0]
 			etl[et] = &EventType{ID: et}
+			r.announced[et] = true
 		}
 
 		r.EventTypes = etl
@@ -183,7 +235,8 @@ func FindEventTypes(xmlReader io.Reader, etl *EventTypeList) int {
 				vet, err := validatexEventType(et)
 				if err != nil {
 					log.Debugf(err.Error())
-					delete(*etl, et.ID)
+					// Keep a placeholder to report the problems
+					(*etl)[et.ID] = invalidEventType(vet, et)
 					break
 				}
 				(*etl)[et.ID] = &vet
@@ -196,130 +249,161 @@ func FindEventTypes(xmlReader io.Reader, etl *EventTypeList) int {
 	return found
 }
 
+// validatexEventType converts a raw xml EventType. Problems found are recorded in EventType.Diagnostics,
+// an error is returned if the EventType is unusable.
 func validatexEventType(xet xEventType) (EventType, error) {
 	var et EventType
 
+	diag := func(kind DiagnosticKind, fatal bool, format string, a ...interface{}) {
+		et.Diagnostics = append(et.Diagnostics, Diagnostic{EventType: et.ID, Kind: kind, Fatal: fatal, Message: fmt.Sprintf(format, a...)})
+	}
+	parseUint8 := func(field string, s string) uint8 {
+		i, err := strconv.ParseUint(s, 0, 8)
+		if err != nil && s != "" {
+			diag(DiagInvalidField, false, "can't parse %v '%v' of EventType %v, using 0", field, s, et.ID)
+		}
+		return uint8(i)
+	}
+	parseFloat32 := func(field string, s string) float32 {
+		f, err := strconv.ParseFloat(s, 32)
+		if err != nil && s != "" {
+			diag(DiagInvalidField, false, "can't parse %v '%v' of EventType %v, using 0", field, s, et.ID)
+			return 0
+		}
+		return float32(f)
+	}
+	parseMode := func(s string) (CommandType, Access) {
+		if _, ok := accessModes[s]; !ok && s != "" {
+			diag(DiagUnknownAccessMode, false, "unknown access mode '%v' of EventType %v", s, et.ID)
+		}
+		return str2CmdType(s)
+	}
+
 	et.ID = xet.ID
 	i, err := strconv.ParseUint(xet.Address, 0, 16)
 	if err != nil {
-		return et, fmt.Errorf("can't parse address '%v' of EventType %v", xet.Address, et.ID)
+		diag(DiagInvalidAddress, true, "can't parse address '%v' of EventType %v", xet.Address, et.ID)
+		return et, et.Diagnostics.Err()
 	}
 	et.Address = AddressT(i)
 
 	et.Description = xet.Description
-	et.FCRead = str2CmdType(xet.FCRead)
-	et.FCWrite = str2CmdType(xet.FCWrite)
+	var readAccess, writeAccess Access
+	et.FCRead, readAccess = parseMode(xet.FCRead)
+	et.FCWrite, writeAccess = parseMode(xet.FCWrite)
+	et.Access = readAccess | writeAccess
+	// Unsupported modes like Remote_Procedure_Call are kept for reference, though they allow no access
+	if knownAccessMode(xet.FCRead) {
+		et.ReadMode = xet.FCRead
+	}
+	if knownAccessMode(xet.FCWrite) {
+		et.WriteMode = xet.FCWrite
+	}
 
 	et.Parameter = xet.Parameter
 
-	p, err := hex.DecodeString(xet.PrefixRead)
+	p, err := parseHexBytes(xet.PrefixRead)
 	if err == nil {
 		et.PrefixRead = p
+	} else {
+		diag(DiagInvalidField, false, "can't parse PrefixRead '%v' of EventType %v", xet.PrefixRead, et.ID)
 	}
-	p, err = hex.DecodeString(xet.PrefixWrite)
+	p, err = parseHexBytes(xet.PrefixWrite)
 	if err == nil {
 		et.PrefixWrite = p
+	} else {
+		diag(DiagInvalidField, false, "can't parse PrefixWrite '%v' of EventType %v", xet.PrefixWrite, et.ID)
 	}
 
-	i, err = strconv.ParseUint(xet.BlockLength, 0, 8)
-	if err == nil {
-		et.BlockLength = uint8(i)
-	}
-	i, err = strconv.ParseUint(xet.BlockFactor, 0, 8)
-	if err == nil {
-		et.BlockFactor = uint8(i)
-	}
-	i, err = strconv.ParseUint(xet.MappingType, 0, 8)
-	if err == nil {
-		et.MappingType = uint8(i)
-	}
-	i, err = strconv.ParseUint(xet.BytePosition, 0, 8)
-	if err == nil {
-		et.BytePosition = uint8(i)
-	}
-	i, err = strconv.ParseUint(xet.ByteLength, 0, 8)
-	if err == nil {
-		et.ByteLength = uint8(i)
-	}
-	i, err = strconv.ParseUint(xet.BitPosition, 0, 8)
-	if err == nil {
-		et.BitPosition = uint8(i)
-	}
-	i, err = strconv.ParseUint(xet.BitLength, 0, 8)
-	if err == nil {
-		et.BitLength = uint8(i)
-	}
+	et.BlockLength = parseUint8("BlockLength", xet.BlockLength)
+	et.BlockFactor = parseUint8("BlockFactor", xet.BlockFactor)
+	et.MappingType = parseUint8("MappingType", xet.MappingType)
+	et.BytePosition = parseUint8("BytePosition", xet.BytePosition)
+	et.ByteLength = parseUint8("ByteLength", xet.ByteLength)
+	et.BitPosition = parseUint8("BitPosition", xet.BitPosition)
+	et.BitLength = parseUint8("BitLength", xet.BitLength)
 
 	et.ALZ = xet.ALZ
 
 	et.Conversion = xet.Conversion
 
-	f, errF := strconv.ParseFloat(xet.ConversionFactor, 32)
-	if errF == nil {
-		et.ConversionFactor = float32(f)
-	}
-	f, errF = strconv.ParseFloat(xet.ConversionOffset, 32)
-	if errF == nil {
-		et.ConversionOffset = float32(f)
-	}
-	f, errF = strconv.ParseFloat(xet.LowerBorder, 32)
-	if errF == nil {
-		et.LowerBorder = float32(f)
-	}
-	f, errF = strconv.ParseFloat(xet.UpperBorder, 32)
-	if errF == nil {
-		et.UpperBorder = float32(f)
+	et.ConversionFactor = parseFloat32("ConversionFactor", xet.ConversionFactor)
+	et.ConversionOffset = parseFloat32("ConversionOffset", xet.ConversionOffset)
+	et.LowerBorder = parseFloat32("LowerBorder", xet.LowerBorder)
+	et.UpperBorder = parseFloat32("UpperBorder", xet.UpperBorder)
+	if f := parseFloat32("Stepping", xet.Stepping); f > 0 {
+		et.Stepping = f
 	}
 
 	et.ValueList = xet.ValueList
 	et.Unit = xet.Unit
 
 	if et.BlockLength < et.BytePosition+et.ByteLength {
-		err = fmt.Errorf("BlockLength mismatch: BlockLength:%v < BytePosition:%v + ByteLength:%v", et.BlockLength, et.BytePosition, et.ByteLength)
+		diag(DiagBlockLengthMismatch, true, "BlockLength mismatch: BlockLength:%v < BytePosition:%v + ByteLength:%v", et.BlockLength, et.BytePosition, et.ByteLength)
+	}
+
+	c, errC := builtinCodec(&et)
+	if rc, ok := LookupCodec(et.Conversion, et.MappingType); ok {
+		// Registered codecs take precedence, even for conversions the built-in codecs can't handle
+		c, errC = rc, nil
+	}
+	et.Codec = c
+	if errC != nil {
+		diag(DiagUnsupportedConversion, true, "%v", errC)
+	} else {
+		var errD error
+		et.Default, errD = et.decodeDefault()
+		if errD != nil {
+			diag(DiagInvalidDefault, false, "%v", errD)
+		}
 	}
 
+	return et, et.Diagnostics.Err()
+}
+
+// builtinCodec returns the built-in Codec for an EventType and fixes up conversion related fields where needed
+func builtinCodec(et *EventType) (c Codec, err error) {
 	// TODO: sort often used ones to top of switch statement
 	switch et.Conversion {
 	case "DateTimeBCD":
-		et.Codec = dateTimeBCDCodec{}
+		c = DateTimeBCDCodec{}
 	case "DateBCD":
-		et.Codec = dateBCDCodec{}
+		c = DateBCDCodec{}
 	case "Sec2Hour":
-		et.Codec = sec2DurationCodec{}
-		et.Unit = "time.Duration"
+		c = Sec2HourCodec{}
+		et.Unit = "h"
 	case "Sec2Minute":
-		et.Codec = sec2DurationCodec{}
-		et.Unit = "time.Duration"
+		c = Sec2MinuteCodec{}
+		et.Unit = "min"
 	case "HourDiffSec2Hour":
-		// TODO: Check if this is correct
-		et.Codec = sec2DurationCodec{}
-		et.Unit = "time.Duration"
+		c = HourDiffSec2HourCodec{}
+		et.Unit = "h"
 	case "Div10":
-		et.Codec = divMulOffsetCodec{}
+		c = DivMulOffsetCodec{}
 		et.ConversionFactor = 1.0 / 10
 	case "Div100":
-		et.Codec = divMulOffsetCodec{}
+		c = DivMulOffsetCodec{}
 		et.ConversionFactor = 1.0 / 100
 	case "Div1000":
-		et.Codec = divMulOffsetCodec{}
+		c = DivMulOffsetCodec{}
 		et.ConversionFactor = 1.0 / 1000
 	case "Div2":
-		et.Codec = divMulOffsetCodec{}
+		c = DivMulOffsetCodec{}
 		et.ConversionFactor = 1.0 / 2
 	case "Mult10":
-		et.Codec = divMulOffsetCodec{}
+		c = DivMulOffsetCodec{}
 		et.ConversionFactor = 10.0
 	case "Mult100":
-		et.Codec = divMulOffsetCodec{}
+		c = DivMulOffsetCodec{}
 		et.ConversionFactor = 100.0
 	case "Mult2":
-		et.Codec = divMulOffsetCodec{}
+		c = DivMulOffsetCodec{}
 		et.ConversionFactor = 2.0
 	case "Mult5":
-		et.Codec = divMulOffsetCodec{}
+		c = DivMulOffsetCodec{}
 		et.ConversionFactor = 5.0
 	case "MultOffset":
-		et.Codec = divMulOffsetCodec{}
+		c = DivMulOffsetCodec{}
 		// Fix missing value:
 		if et.ConversionFactor == 0 {
 			et.ConversionFactor = 1.0
@@ -331,142 +415,112 @@ func validatexEventType(xet xEventType) (EventType, error) {
 
 	case "NoConversion":
 		if len(et.ValueList) > 0 {
-			et.Codec = valueListCodec{}
+			c = ValueListCodec{}
 		} else if et.MappingType > 0 {
 			switch et.MappingType {
 			case 1:
-				et.Codec = mappingTime53{}
+				c = MappingTime53{}
 				et.Unit = "time.Duration"
 			case 2:
-				et.Codec = mappingRaster152{}
+				c = MappingRaster152{}
 				et.Unit = "time.Duration"
 			case 3:
-				et.Codec = mappingErrors{}
+				c = MappingErrors{}
 			default:
-				et.Codec = nopCodec{}
+				c = NopCodec{}
 			}
-		} else if et.ByteLength < 5 && et.BitLength == 0 {
+		} else if et.BitLength > 0 || et.ByteLength < 5 {
+			// Plain numbers and bit fields
 			// Fix missing value:
 			if et.ConversionFactor == 0 {
 				et.ConversionFactor = 1.0
 			}
-			et.Codec = divMulOffsetCodec{}
-		} else if et.BlockLength == 9 && et.ID[0:11] == "FehlerHisFA" {
-			et.Codec = mappingErrors{}
+			c = DivMulOffsetCodec{}
+		} else if et.BlockLength == 9 && strings.HasPrefix(et.ID, "FehlerHisFA") {
+			c = MappingErrors{}
 		} else {
-			et.Codec = nopCodec{}
+			c = NopCodec{}
 		}
 	default:
-		//et.Codec = nopCodec{}
+		//c = NopCodec{}
 		err = fmt.Errorf("can't handle %v conversion in EventType %v", et.Conversion, et.ID)
 
 	}
 
-	return et, err
+	return c, err
 }
 
-func str2CmdType(s string) CommandType {
-	var c CommandType
-	var readWrite byte // 0 == undefined, 1 == read, 2 == write, 3==bidirectional/rpc
-
-	// TODO: find out more CommandType mappings
-	switch s {
-	case "BE_READ":
-		c = nop
-		readWrite = 0x01
-	case "BE_WRITE":
-		c = nop
-		readWrite = 0x02
-	case "EEPROM_READ":
-		c = nop
-		readWrite = 0x01
-	case "EEPROM_WRITE":
-		c = nop
-		readWrite = 0x02
-	case "KBUS_DATAELEMENT_READ":
-		c = nop
-		readWrite = 0x01
-	case "KBUS_DIRECT_READ":
-		c = nop
-		readWrite = 0x01
-	case "KBUS_DIRECT_WRITE":
-		c = nop
-		readWrite = 0x02
-	case "KBUS_EEPROM_LT_READ":
-		c = nop
-		readWrite = 0x01
-	case "KBUS_EEPROM_LT_WRITE":
-		c = nop
-		readWrite = 0x02
-	case "KBUS_GATEWAY_WRITE":
-		c = nop
-		readWrite = 0x02
-	case "KBUS_INDIRECT_READ":
-		c = nop
-		readWrite = 0x01
-	case "KBUS_INDIRECT_WRITE":
-		c = nop
-		readWrite = 0x02
-	case "KBUS_MEMBERLIST_READ":
-		c = nop
-		readWrite = 0x01
-	case "KBUS_MEMBERLIST_WRITE":
-		c = nop
-		readWrite = 0x02
-	case "KBUS_TRANSPARENT_READ":
-		c = nop
-		readWrite = 0x01
-	case "KBUS_TRANSPARENT_WRITE":
-		c = nop
-		readWrite = 0x02
-	case "KBUS_VIRTUAL_READ":
-		c = nop
-		readWrite = 0x01
-	case "KBUS_VIRTUAL_WRITE":
-		c = nop
-		readWrite = 0x02
-	case "KMBUS_EEPROM_READ":
-		c = nop
-		readWrite = 0x01
-	case "Physical_READ":
-		c = nop
-		readWrite = 0x01
-	case "Port_READ":
-		c = nop
-		readWrite = 0x01
-	case "Remote_Procedure_Call":
-		// TODO: Is this p300FunctionCall (0x07)?
-		c = nop
-		readWrite = 0x00 // TODO: uses 0x07?
-	case "Virtual_MBUS":
-		c = nop
-		readWrite = 0x03
-	case "Virtual_MarktManager_READ":
-		c = nop
-		readWrite = 0x01
-	case "Virtual_MarktManager_WRITE":
-		c = nop
-		readWrite = 0x01
-	case "Virtual_READ":
-		c = p300ReadData
-		readWrite = 0x01
-	case "Virtual_WRITE":
-		c = p300WriteData
-		readWrite = 0x02
-	case "Virtual_WILO_READ":
-		c = nop
-		readWrite = 0x01
-	case "Virtual_WILO_WRITE":
-		c = nop
-		readWrite = 0x02
-	case "undefined":
-		c = nop
-		readWrite = 0x00
-	default:
-		c = nop
-		readWrite = 0x00
+// parseHexBytes parses hex strings like "0A2F", "0x0A2F" or "0A 2F"
+func parseHexBytes(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0x"), "0X")
+	s = strings.ReplaceAll(s, " ", "")
+	return hex.DecodeString(s)
+}
+
+// accessModes maps the access modes used in ecnEventType.xml to command types and access directions.
+// Modes without a command type can't be sent, so they allow no access.
+var accessModes = map[string]struct {
+	cmd    CommandType
+	access Access
+}{
+	"BE_READ":                    {physicalBeRead, AccessRead},
+	"BE_WRITE":                   {physicalBeWrite, AccessWrite},
+	"EEPROM_READ":                {eepromRead, AccessRead},
+	"EEPROM_WRITE":               {eepromWrite, AccessWrite},
+	"KBUS_DATAELEMENT_READ":      {p300KbusDataElementRead, AccessRead},
+	"KBUS_DATAELEMENT_WRITE":     {p300KbusDataElementWrite, AccessWrite},
+	"KBUS_DIRECT_READ":           {p300KbusDirectRead, AccessRead},
+	"KBUS_DIRECT_WRITE":          {p300KbusDirectWrite, AccessWrite},
+	"KBUS_EEPROM_LT_READ":        {p300KbusEepromLtRead, AccessRead},
+	"KBUS_EEPROM_LT_WRITE":       {p300KbusEepromLtWrite, AccessWrite},
+	"KBUS_GATEWAY_READ":          {p300KbusGatewayRead, AccessRead},
+	"KBUS_GATEWAY_WRITE":         {p300KbusGatewayWrite, AccessWrite},
+	"KBUS_INDIRECT_READ":         {p300KbusIndirectRead, AccessRead},
+	"KBUS_INDIRECT_WRITE":        {p300KbusIndirectWrite, AccessWrite},
+	"KBUS_MEMBERLIST_READ":       {p300KbusMemberListRead, AccessRead},
+	"KBUS_MEMBERLIST_WRITE":      {p300KbusMemberListWrite, AccessWrite},
+	"KBUS_TRANSPARENT_READ":      {p300KbusTransparentRead, AccessRead},
+	"KBUS_TRANSPARENT_WRITE":     {p300KbusTransparentWrite, AccessWrite},
+	"KBUS_VIRTUAL_READ":          {p300KbusVirtualRead, AccessRead},
+	"KBUS_VIRTUAL_WRITE":         {p300KbusVirtualWrite, AccessWrite},
+	"KMBUS_EEPROM_READ":          {physicalKmBusEepromRead, AccessRead},
+	"KMBUS_RAM_READ":             {physicalKmbusRAMRead, AccessRead},
+	"Physical_READ":              {physicalRead, AccessRead},
+	"Physical_WRITE":             {physicalWrite, AccessWrite},
+	"Port_READ":                  {physicalPortRead, AccessRead},
+	"Port_WRITE":                 {physicalPortWrite, AccessWrite},
+	"XRAM_READ":                  {physicalXramRead, AccessRead},
+	"XRAM_WRITE":                 {physicalXramWrite, AccessWrite},
+	"Remote_Procedure_Call":      {nop, AccessNone}, // TODO: Is this p300FunctionCall (0x07)?
+	"Virtual_MBUS":               {nop, AccessNone},
+	"Virtual_MarktManager_READ":  {p300VirtualMarktManagerRead, AccessRead},
+	"Virtual_MarktManager_WRITE": {p300VirtualMarktManagerWrite, AccessWrite},
+	"Virtual_READ":               {p300ReadData, AccessRead},
+	"Virtual_WRITE":              {p300WriteData, AccessWrite},
+	"Virtual_WILO_READ":          {p300VirtualWiloRead, AccessRead},
+	"Virtual_WILO_WRITE":         {p300VirtualWiloWrite, AccessWrite},
+	"undefined":                  {nop, AccessNone},
+}
+
+// knownAccessMode returns true for the access modes of ecnEventType.xml except "undefined"
+func knownAccessMode(s string) bool {
+	_, ok := accessModes[s]
+	return ok && s != "undefined"
+}
+
+// str2CmdType returns the command type and the access direction of an access mode
+func str2CmdType(s string) (CommandType, Access) {
+	if m, ok := accessModes[s]; ok {
+		return m.cmd, m.access
 	}
+	return nop, AccessNone
+}
 
-	_ = readWrite // TODO: Remove or make use of readWrite
-	return c
+// SupportedAccessModes lists the access modes of ecnEventType.xml and whether they can be used with the given protocol
+func SupportedAccessModes(p Protocol) map[string]bool {
+	r := make(map[string]bool, len(accessModes))
+	for s, m := range accessModes {
+		r[s] = cmdSupported(m.cmd, p)
+	}
+	return r
 }
@@ -0,0 +1,38 @@
+package vogo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAccessModes(t *testing.T) {
+	tests := []struct {
+		read, write string
+		want        Access
+	}{
+		{"Virtual_READ", "Virtual_WRITE", AccessReadWrite},
+		{"Virtual_READ", "undefined", AccessRead},
+		{"undefined", "Virtual_WRITE", AccessWrite},
+		{"Remote_Procedure_Call", "Remote_Procedure_Call", AccessNone},
+		{"Virtual_MBUS", "Virtual_MBUS", AccessNone},
+		{"Virtual_READ", "Virtual_MBUS", AccessRead},
+	}
+
+	for _, tt := range tests {
+		xml := fmt.Sprintf(`<?xml version="1.0"?>
+<DocumentElement>
+<EventType><ID>Mode~0x2306</ID><Address>0x2306</Address><FCRead>%v</FCRead><FCWrite>%v</FCWrite><Parameter>Byte</Parameter><BlockLength>1</BlockLength><ByteLength>1</ByteLength><BlockFactor>0</BlockFactor><MappingType>0</MappingType><BytePosition>0</BytePosition><BitPosition>0</BitPosition><BitLength>0</BitLength><Conversion>NoConversion</Conversion></EventType>
+</DocumentElement>`, tt.read, tt.write)
+		etl := EventTypeList{"Mode": nil}
+		FindEventTypes(strings.NewReader(xml), &etl)
+		et := etl["Mode"]
+		if et == nil || et.Access != tt.want {
+			t.Errorf("%v/%v: got %+v, want access %v", tt.read, tt.write, et, tt.want)
+			continue
+		}
+		if tt.read != "undefined" && et.ReadMode != tt.read {
+			t.Errorf("%v/%v: ReadMode %q", tt.read, tt.write, et.ReadMode)
+		}
+	}
+}